@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "manage api keys",
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "create a new api key",
+	Run:   createApiKey,
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list all api keys",
+	Run:   listApiKeys,
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke [uuid]",
+	Short: "revoke an api key and its sessions",
+	Args:  cobra.ExactArgs(1),
+	Run:   revokeApiKey,
+}
+
+func init() {
+	if runtime.GOOS == "windows" {
+		apiKeyCmd.PersistentFlags().StringP("database", "b", "%APPDATA%\\ffmate\\db.sql", "the path do the database")
+	} else {
+		apiKeyCmd.PersistentFlags().StringP("database", "b", "~/.ffmate/db.sqlite", "the path do the database")
+	}
+
+	apiKeyCreateCmd.Flags().StringP("name", "n", "", "name of the api key")
+	apiKeyCreateCmd.Flags().StringSliceP("scopes", "s", []string{string(dto.SCOPE_READ)}, "scopes of the api key (read, submit, admin)")
+
+	apiKeyCmd.AddCommand(apiKeyCreateCmd, apiKeyListCmd, apiKeyRevokeCmd)
+	rootCmd.AddCommand(apiKeyCmd)
+}
+
+// the flag is read directly as the database key of viper is bound to the server flag
+func openAuthDatabase(cmd *cobra.Command) *sev.Sev {
+	database, _ := cmd.Flags().GetString("database")
+	s := sev.New("ffmate", config.Config().AppVersion, database, 0)
+	(&repository.ApiKey{DB: s.DB()}).Setup()
+	(&repository.User{DB: s.DB()}).Setup()
+	return s
+}
+
+func createApiKey(cmd *cobra.Command, args []string) {
+	name, _ := cmd.Flags().GetString("name")
+	scopes, _ := cmd.Flags().GetStringSlice("scopes")
+
+	newApiKey := &dto.NewApiKey{Name: name}
+	for _, scope := range scopes {
+		newApiKey.Scopes = append(newApiKey.Scopes, dto.Scope(scope))
+	}
+
+	s := openAuthDatabase(cmd)
+	key, apiKey, err := service.NewAuthService(s).CreateApiKey(newApiKey)
+	if err != nil {
+		s.Logger().Errorf("failed to create api key: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("uuid:   %s\nscopes: %s\nkey:    %s\n\nstore the key safely, it cannot be shown again\n", apiKey.Uuid, joinScopes(apiKey.Scopes), key)
+}
+
+func listApiKeys(cmd *cobra.Command, args []string) {
+	s := openAuthDatabase(cmd)
+	apiKeys, err := service.NewAuthService(s).ListApiKeys()
+	if err != nil {
+		s.Logger().Errorf("failed to list api keys: %v", err)
+		os.Exit(1)
+	}
+
+	for _, apiKey := range *apiKeys {
+		fmt.Printf("%s  %s...  %-20s %s\n", apiKey.Uuid, apiKey.Prefix, apiKey.Name, joinScopes(apiKey.Scopes))
+	}
+}
+
+func revokeApiKey(cmd *cobra.Command, args []string) {
+	s := openAuthDatabase(cmd)
+	if err := service.NewAuthService(s).RevokeApiKey(args[0]); err != nil {
+		s.Logger().Errorf("failed to revoke api key: %v", err)
+		os.Exit(1)
+	}
+}
+
+func joinScopes(scopes []dto.Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/metrics"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export presets, watchfolders and webhooks as bundle",
+	Run:   exportBundle,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "import presets, watchfolders and webhooks from a bundle",
+	Args:  cobra.ExactArgs(1),
+	Run:   importBundle,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{exportCmd, importCmd} {
+		if runtime.GOOS == "windows" {
+			cmd.Flags().StringP("database", "b", "%APPDATA%\\ffmate\\db.sql", "the path do the database")
+		} else {
+			cmd.Flags().StringP("database", "b", "~/.ffmate/db.sqlite", "the path do the database")
+		}
+	}
+
+	exportCmd.Flags().StringP("output", "o", "", "file to write the bundle to (default: stdout)")
+	exportCmd.Flags().StringP("format", "f", "yaml", "format of the bundle (yaml, json)")
+
+	importCmd.Flags().StringP("conflict", "c", string(dto.CONFLICT_SKIP), "how to handle existing resources (skip, overwrite, rename)")
+	importCmd.Flags().BoolP("dry-run", "", false, "only print what would be imported")
+
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
+
+// the flag is read directly as the database key of viper is bound to the server flag
+func openBundleDatabase(cmd *cobra.Command) *sev.Sev {
+	database, _ := cmd.Flags().GetString("database")
+	s := sev.New("ffmate", config.Config().AppVersion, database, 0)
+	(&repository.Preset{DB: s.DB()}).Setup()
+	(&repository.Watchfolder{DB: s.DB()}).Setup()
+	(&repository.Webhook{DB: s.DB()}).Setup()
+	(&repository.WebhookDelivery{DB: s.DB()}).Setup()
+	(&repository.Audit{DB: s.DB()}).Setup()
+
+	metrics := &metrics.Metrics{}
+	for name, gauge := range metrics.Gauges() {
+		s.Metrics().RegisterGauge(name, gauge)
+	}
+	for name, gauge := range metrics.GaugesVec() {
+		s.Metrics().RegisterGaugeVec(name, gauge)
+	}
+
+	service.Init(s)
+	return s
+}
+
+func exportBundle(cmd *cobra.Command, args []string) {
+	output, _ := cmd.Flags().GetString("output")
+	format, _ := cmd.Flags().GetString("format")
+
+	s := openBundleDatabase(cmd)
+	bundle, err := service.BundleService().Export()
+	if err != nil {
+		s.Logger().Errorf("failed to export bundle: %v", err)
+		os.Exit(1)
+	}
+	b, err := service.MarshalBundle(bundle, format)
+	if err != nil {
+		s.Logger().Errorf("failed to export bundle: %v", err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		fmt.Print(string(b))
+		return
+	}
+	if err := os.WriteFile(output, b, 0600); err != nil {
+		s.Logger().Errorf("failed to write bundle: %v", err)
+		os.Exit(1)
+	}
+}
+
+func importBundle(cmd *cobra.Command, args []string) {
+	conflict, _ := cmd.Flags().GetString("conflict")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	s := openBundleDatabase(cmd)
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		s.Logger().Errorf("failed to read bundle: %v", err)
+		os.Exit(1)
+	}
+	bundle, err := service.ParseBundle(data)
+	if err != nil {
+		s.Logger().Errorf("failed to import bundle: %v", err)
+		os.Exit(1)
+	}
+	result, err := service.BundleService().Import(bundle, dto.BundleConflict(conflict), dryRun, nil)
+	if err != nil {
+		s.Logger().Errorf("failed to import bundle: %v", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, item := range result.Items {
+		fmt.Printf("%-10s %-12s %-36s %s", item.Action, item.Resource, item.Target, item.Name)
+		if item.Error != "" {
+			failed = true
+			fmt.Printf(": %s", item.Error)
+		}
+		fmt.Println()
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -42,6 +42,7 @@ func init() {
 	rootCmd.AddCommand(serverCmd)
 
 	serverCmd.PersistentFlags().StringP("ffmpeg", "f", "ffmpeg", "path to ffmpeg binary")
+	serverCmd.PersistentFlags().StringP("ffprobe", "", "ffprobe", "path to ffprobe binary (used by ${probe.*} wildcards)")
 	serverCmd.PersistentFlags().StringP("port", "p", "3000", "the port to listen to")
 	serverCmd.PersistentFlags().BoolP("tray", "t", false, "start with tray menu (experimental)")
 	if runtime.GOOS == "windows" {
@@ -52,14 +53,41 @@ func init() {
 	serverCmd.PersistentFlags().UintP("max-concurrent-tasks", "m", 3, "define maximum concurrent running tasks")
 	serverCmd.PersistentFlags().StringP("ai", "", "", "ai vendor:model:key")
 	serverCmd.PersistentFlags().BoolP("send-telemetry", "s", true, "enable sending anonymous telemetry data")
+	serverCmd.PersistentFlags().BoolP("auth", "", false, "require an api key or session token for the api, metrics and websocket")
+	serverCmd.PersistentFlags().StringSliceP("cors-origins", "", []string{"*"}, "origins allowed to access the api from a browser")
+	serverCmd.PersistentFlags().StringSliceP("input-roots", "", []string{}, "directories input files and watchfolders must be inside of (default: unrestricted)")
+	serverCmd.PersistentFlags().StringSliceP("output-roots", "", []string{}, "directories output, sidecar and uploaded files must be inside of (default: unrestricted)")
+	serverCmd.PersistentFlags().StringSliceP("script-dirs", "", []string{}, "directories pre- and postProcessing scripts must be inside of (default: unrestricted)")
+	serverCmd.PersistentFlags().StringSliceP("allowed-options", "", []string{}, "ffmpeg options commands may use (default: all)")
+	serverCmd.PersistentFlags().StringSliceP("denied-options", "", []string{}, "ffmpeg options commands must not use")
+	serverCmd.PersistentFlags().StringSliceP("allowed-protocols", "", []string{}, "protocols ffmpeg inputs and outputs may use (default: all)")
+	serverCmd.PersistentFlags().StringSliceP("denied-protocols", "", []string{}, "protocols ffmpeg inputs and outputs must not use")
+	serverCmd.PersistentFlags().StringSliceP("allowed-filters", "", []string{}, "ffmpeg filters commands may use (default: all)")
+	serverCmd.PersistentFlags().StringSliceP("denied-filters", "", []string{}, "ffmpeg filters commands must not use")
+	serverCmd.PersistentFlags().StringP("config-dir", "", "", "directory of yaml/json files declaring presets, watchfolders and webhooks, they are applied on startup and on change and read-only via the api")
+	serverCmd.PersistentFlags().DurationP("webhook-delivery-retention", "", 7*24*time.Hour, "how long finished webhook deliveries are kept (0 keeps them forever)")
 
 	viper.BindPFlag("ffmpeg", serverCmd.PersistentFlags().Lookup("ffmpeg"))
+	viper.BindPFlag("ffprobe", serverCmd.PersistentFlags().Lookup("ffprobe"))
 	viper.BindPFlag("port", serverCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("tray", serverCmd.PersistentFlags().Lookup("tray"))
 	viper.BindPFlag("database", serverCmd.PersistentFlags().Lookup("database"))
 	viper.BindPFlag("maxConcurrentTasks", serverCmd.PersistentFlags().Lookup("max-concurrent-tasks"))
 	viper.BindPFlag("ai", serverCmd.PersistentFlags().Lookup("ai"))
 	viper.BindPFlag("sendTelemetry", serverCmd.PersistentFlags().Lookup("send-telemetry"))
+	viper.BindPFlag("auth", serverCmd.PersistentFlags().Lookup("auth"))
+	viper.BindPFlag("corsOrigins", serverCmd.PersistentFlags().Lookup("cors-origins"))
+	viper.BindPFlag("inputRoots", serverCmd.PersistentFlags().Lookup("input-roots"))
+	viper.BindPFlag("outputRoots", serverCmd.PersistentFlags().Lookup("output-roots"))
+	viper.BindPFlag("scriptDirs", serverCmd.PersistentFlags().Lookup("script-dirs"))
+	viper.BindPFlag("allowedOptions", serverCmd.PersistentFlags().Lookup("allowed-options"))
+	viper.BindPFlag("deniedOptions", serverCmd.PersistentFlags().Lookup("denied-options"))
+	viper.BindPFlag("allowedProtocols", serverCmd.PersistentFlags().Lookup("allowed-protocols"))
+	viper.BindPFlag("deniedProtocols", serverCmd.PersistentFlags().Lookup("denied-protocols"))
+	viper.BindPFlag("allowedFilters", serverCmd.PersistentFlags().Lookup("allowed-filters"))
+	viper.BindPFlag("deniedFilters", serverCmd.PersistentFlags().Lookup("denied-filters"))
+	viper.BindPFlag("configDir", serverCmd.PersistentFlags().Lookup("config-dir"))
+	viper.BindPFlag("webhookDeliveryRetention", serverCmd.PersistentFlags().Lookup("webhook-delivery-retention"))
 }
 
 func start(cmd *cobra.Command, args []string) {
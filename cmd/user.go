@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "manage users",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "create a new user",
+	Run:   createUser,
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list all users",
+	Run:   listUsers,
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [uuid]",
+	Short: "delete a user with its api keys and sessions",
+	Args:  cobra.ExactArgs(1),
+	Run:   deleteUser,
+}
+
+func init() {
+	if runtime.GOOS == "windows" {
+		userCmd.PersistentFlags().StringP("database", "b", "%APPDATA%\\ffmate\\db.sql", "the path do the database")
+	} else {
+		userCmd.PersistentFlags().StringP("database", "b", "~/.ffmate/db.sqlite", "the path do the database")
+	}
+
+	userCreateCmd.Flags().StringP("username", "u", "", "name of the user")
+	userCreateCmd.Flags().StringP("password", "p", "", "password of the user (at least 8 characters)")
+	userCreateCmd.Flags().StringP("role", "r", string(dto.ROLE_VIEWER), "role of the user (viewer, operator, admin)")
+
+	userCmd.AddCommand(userCreateCmd, userListCmd, userDeleteCmd)
+	rootCmd.AddCommand(userCmd)
+}
+
+func createUser(cmd *cobra.Command, args []string) {
+	username, _ := cmd.Flags().GetString("username")
+	password, _ := cmd.Flags().GetString("password")
+	role, _ := cmd.Flags().GetString("role")
+
+	s := openAuthDatabase(cmd)
+	user, err := service.NewAuthService(s).CreateUser(&dto.NewUser{Username: username, Password: password, Role: dto.Role(role)})
+	if err != nil {
+		s.Logger().Errorf("failed to create user: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("uuid: %s\nrole: %s\n", user.Uuid, user.Role)
+}
+
+func listUsers(cmd *cobra.Command, args []string) {
+	s := openAuthDatabase(cmd)
+	users, err := service.NewAuthService(s).ListUsers()
+	if err != nil {
+		s.Logger().Errorf("failed to list users: %v", err)
+		os.Exit(1)
+	}
+
+	for _, user := range *users {
+		fmt.Printf("%s  %-20s %s\n", user.Uuid, user.Username, user.Role)
+	}
+}
+
+func deleteUser(cmd *cobra.Command, args []string) {
+	s := openAuthDatabase(cmd)
+	if err := service.NewAuthService(s).DeleteUser(args[0]); err != nil {
+		s.Logger().Errorf("failed to delete user: %v", err)
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -11,7 +12,8 @@ type ConfigDefinition struct {
 	AppName    string `mapstructure:"appName"`
 	AppVersion string `mapstructure:"appVersion"`
 
-	FFMpeg string `mapstructure:"ffmpeg"`
+	FFMpeg  string `mapstructure:"ffmpeg"`
+	FFProbe string `mapstructure:"ffprobe"`
 
 	Port               uint   `mapstructure:"port"`
 	Tray               bool   `mapstructure:"tray"`
@@ -22,16 +24,37 @@ type ConfigDefinition struct {
 	SendTelemetry      bool   `mapstructure:"sendTelemetry"`
 
 	AI string `mapstructure:"ai"`
+
+	Auth        bool     `mapstructure:"auth"`
+	CorsOrigins []string `mapstructure:"corsOrigins"`
+
+	InputRoots  []string `mapstructure:"inputRoots"`
+	OutputRoots []string `mapstructure:"outputRoots"`
+	ScriptDirs  []string `mapstructure:"scriptDirs"`
+
+	AllowedOptions   []string `mapstructure:"allowedOptions"`
+	DeniedOptions    []string `mapstructure:"deniedOptions"`
+	AllowedProtocols []string `mapstructure:"allowedProtocols"`
+	DeniedProtocols  []string `mapstructure:"deniedProtocols"`
+	AllowedFilters   []string `mapstructure:"allowedFilters"`
+	DeniedFilters    []string `mapstructure:"deniedFilters"`
+
+	ConfigDir string `mapstructure:"configDir"`
+
+	WebhookDeliveryRetention time.Duration `mapstructure:"webhookDeliveryRetention"`
 }
 
 var config ConfigDefinition
 
 func Init() {
-	err := viper.Unmarshal(&config)
+	// unmarshal into a fresh value as empty lists would not replace previous values
+	var c ConfigDefinition
+	err := viper.Unmarshal(&c)
 	if err != nil {
 		fmt.Printf("failed to unmarshal config: %s\n", err)
 		os.Exit(1)
 	}
+	config = c
 
 	if config.Debug == "" {
 		config.Debug = os.Getenv("DEBUGO")
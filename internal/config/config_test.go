@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -20,6 +22,7 @@ func TestConfig(t *testing.T) {
 	viper.Set("appName", "TestApp")
 	viper.Set("appVersion", "1.0.0")
 	viper.Set("ffmpeg", "/usr/bin/ffmpeg")
+	viper.Set("ffprobe", "/usr/bin/ffprobe")
 	viper.Set("port", uint(8080))
 	viper.Set("tray", true)
 	viper.Set("database", "/path/to/db.sqlite")
@@ -28,6 +31,19 @@ func TestConfig(t *testing.T) {
 	viper.Set("maxConcurrentTasks", uint(4))
 	viper.Set("sendTelemetry", true)
 	viper.Set("ai", "test:test:test")
+	viper.Set("auth", true)
+	viper.Set("corsOrigins", []string{"https://ffmate.example.com"})
+	viper.Set("inputRoots", []string{"/data/in"})
+	viper.Set("outputRoots", []string{"/data/out"})
+	viper.Set("scriptDirs", []string{"/opt/scripts"})
+	viper.Set("allowedOptions", []string{"c:v", "crf"})
+	viper.Set("deniedOptions", []string{"f"})
+	viper.Set("allowedProtocols", []string{"file", "pipe"})
+	viper.Set("deniedProtocols", []string{"http"})
+	viper.Set("allowedFilters", []string{"scale"})
+	viper.Set("deniedFilters", []string{"movie"})
+	viper.Set("configDir", "/etc/ffmate")
+	viper.Set("webhookDeliveryRetention", "72h")
 
 	Init()
 	c := Config()
@@ -42,6 +58,7 @@ func TestConfig(t *testing.T) {
 		{"AppName", c.AppName, "TestApp", "AppName mismatch"},
 		{"AppVersion", c.AppVersion, "1.0.0", "AppVersion mismatch"},
 		{"FFMpeg", c.FFMpeg, "/usr/bin/ffmpeg", "FFMpeg path mismatch"},
+		{"FFProbe", c.FFProbe, "/usr/bin/ffprobe", "FFProbe path mismatch"},
 		{"Port", c.Port, uint(8080), "Port mismatch"},
 		{"Tray", c.Tray, true, "Tray setting mismatch"},
 		{"Database", c.Database, "/path/to/db.sqlite", "Database path mismatch"},
@@ -50,6 +67,19 @@ func TestConfig(t *testing.T) {
 		{"MaxConcurrentTasks", c.MaxConcurrentTasks, uint(4), "MaxConcurrentTasks mismatch"},
 		{"SendTelemetry", c.SendTelemetry, true, "SendTelemetry mismatch"},
 		{"AI", c.AI, "test:test:test", "AI setting mismatch"},
+		{"Auth", c.Auth, true, "Auth setting mismatch"},
+		{"CorsOrigins", strings.Join(c.CorsOrigins, ","), "https://ffmate.example.com", "CorsOrigins mismatch"},
+		{"InputRoots", strings.Join(c.InputRoots, ","), "/data/in", "InputRoots mismatch"},
+		{"OutputRoots", strings.Join(c.OutputRoots, ","), "/data/out", "OutputRoots mismatch"},
+		{"ScriptDirs", strings.Join(c.ScriptDirs, ","), "/opt/scripts", "ScriptDirs mismatch"},
+		{"AllowedOptions", strings.Join(c.AllowedOptions, ","), "c:v,crf", "AllowedOptions mismatch"},
+		{"DeniedOptions", strings.Join(c.DeniedOptions, ","), "f", "DeniedOptions mismatch"},
+		{"AllowedProtocols", strings.Join(c.AllowedProtocols, ","), "file,pipe", "AllowedProtocols mismatch"},
+		{"DeniedProtocols", strings.Join(c.DeniedProtocols, ","), "http", "DeniedProtocols mismatch"},
+		{"AllowedFilters", strings.Join(c.AllowedFilters, ","), "scale", "AllowedFilters mismatch"},
+		{"DeniedFilters", strings.Join(c.DeniedFilters, ","), "movie", "DeniedFilters mismatch"},
+		{"ConfigDir", c.ConfigDir, "/etc/ffmate", "ConfigDir mismatch"},
+		{"WebhookDeliveryRetention", c.WebhookDeliveryRetention, 72 * time.Hour, "WebhookDeliveryRetention mismatch"},
 	}
 
 	// Run tests and track covered fields
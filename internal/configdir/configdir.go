@@ -0,0 +1,140 @@
+package configdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/yosev/debugo"
+)
+
+// ConfigDir applies the presets, watchfolders and webhooks declared in a directory of yaml and json files on startup and
+// whenever one of the files changes
+type ConfigDir struct {
+	Sev  *sev.Sev
+	Path string
+
+	mutex sync.Mutex
+}
+
+var debug = debugo.New("configdir")
+
+// changes are applied once the directory settled, editors and git write files in several steps
+const settleDelay = time.Second
+
+func (c *ConfigDir) Init() {
+	c.reconcile()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		c.Sev.Logger().Errorf("failed to watch config directory (path: %s): %v", c.Path, err)
+		return
+	}
+	if err := watcher.Add(c.Path); err != nil {
+		c.Sev.Logger().Errorf("failed to watch config directory (path: %s): %v", c.Path, err)
+		watcher.Close()
+		return
+	}
+	c.Sev.RegisterShutdownHook(func(s *sev.Sev) {
+		watcher.Close()
+	})
+
+	go c.watch(watcher)
+}
+
+func (c *ConfigDir) watch(watcher *fsnotify.Watcher) {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !isConfigFile(filepath.Base(event.Name)) || event.Has(fsnotify.Chmod) {
+				continue
+			}
+			debug.Debugf("config file changed (path: %s, op: %s)", event.Name, event.Op)
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(settleDelay, c.reconcile)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			c.Sev.Logger().Warnf("failed to watch config directory (path: %s): %v", c.Path, err)
+		}
+	}
+}
+
+func (c *ConfigDir) reconcile() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// nothing is applied from an incomplete directory, it would delete the resources of the failing files
+	bundle, err := Load(c.Path)
+	if err != nil {
+		c.Sev.Logger().Errorf("failed to load config directory (path: %s): %v", c.Path, err)
+		return
+	}
+	result, err := service.BundleService().Reconcile(bundle)
+	if err != nil {
+		c.Sev.Logger().Errorf("failed to reconcile config directory (path: %s): %v", c.Path, err)
+		return
+	}
+
+	for _, item := range result.Items {
+		switch item.Action {
+		case dto.BUNDLE_FAILED:
+			c.Sev.Logger().Warnf("failed to apply %s '%s' from config directory: %s", item.Resource, item.Name, item.Error)
+		case dto.BUNDLE_SKIP:
+			debug.Debugf("%s '%s' is up to date (uuid: %s)", item.Resource, item.Name, item.Target)
+		default:
+			c.Sev.Logger().Infof("applied %s '%s' from config directory (action: %s, uuid: %s)", item.Resource, item.Name, item.Action, item.Target)
+		}
+	}
+}
+
+// Load merges the bundles of all yaml and json files of a directory, subdirectories are ignored
+func Load(path string) (*dto.Bundle, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &dto.Bundle{Version: dto.BUNDLE_VERSION}
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b, err := service.ParseBundle(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		bundle.Presets = append(bundle.Presets, b.Presets...)
+		bundle.Watchfolders = append(bundle.Watchfolders, b.Watchfolders...)
+		bundle.Webhooks = append(bundle.Webhooks, b.Webhooks...)
+	}
+	return bundle, nil
+}
+
+// isConfigFile skips hidden files, e.g. temporary files of editors
+func isConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return !strings.HasPrefix(name, ".")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,39 @@
+package configdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"presets.yaml":      "version: 1\npresets:\n  - name: H264\n    command: -c:v libx264\n",
+		"watchfolders.json": `{"version": 1, "watchfolders": [{"name": "Inbox", "path": "/in", "preset": "H264"}]}`,
+		".presets.yaml.swp": "not a bundle",
+		"README.md":         "not a bundle",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	bundle, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load config directory: %v", err)
+	}
+	if len(bundle.Presets) != 1 || bundle.Presets[0].Command != "-c:v libx264" {
+		t.Errorf("Unexpected presets %+v", bundle.Presets)
+	}
+	if len(bundle.Watchfolders) != 1 || bundle.Watchfolders[0].Preset != "H264" {
+		t.Errorf("Unexpected watchfolders %+v", bundle.Watchfolders)
+	}
+
+	os.WriteFile(filepath.Join(dir, "webhooks.yml"), []byte("webhooks: ["), 0644)
+	if _, err := Load(dir); err == nil || !strings.HasPrefix(err.Error(), "webhooks.yml:") {
+		t.Errorf("Expected error naming the invalid file, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/welovemedia/ffmate/sev/exceptions"
 )
@@ -20,7 +21,7 @@ type AIController struct {
 
 func (c *AIController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().GET(c.Prefix+c.getEndpoint(), c.getAI)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_TASK_SUBMIT), c.getAI)
 }
 
 // @Summary Get AI configuration
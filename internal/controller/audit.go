@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type AuditController struct {
+	sev.Controller
+	sev *sev.Sev
+
+	Prefix string
+}
+
+func (c *AuditController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_AUDIT_READ), interceptor.PageLimit, c.listAudit)
+}
+
+// @Summary List the audit log
+// @Description List changes of presets, webhooks and watchfolders, newest first
+// @Tags audit
+// @Param resource query string false "filter by resource (preset, webhook, watchfolder)"
+// @Param resourceUuid query string false "filter by the uuid of the changed resource"
+// @Produce json
+// @Success 200 {object} []dto.Audit
+// @Router /audit [get]
+func (c *AuditController) listAudit(gin *gin.Context) {
+	audits, total, err := service.AuditService().ListAudit(gin.GetInt("page"), gin.GetInt("perPage"), gin.Query("resource"), gin.Query("resourceUuid"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/audit"))
+		return
+	}
+
+	gin.Header("X-Total", fmt.Sprintf("%d", total))
+
+	// Transform each entry to its DTO
+	var auditDTOs = []dto.Audit{}
+	for _, audit := range *audits {
+		auditDTOs = append(auditDTOs, *audit.ToDto())
+	}
+
+	gin.JSON(200, auditDTOs)
+}
+
+func (c *AuditController) GetName() string {
+	return "audit"
+}
+
+func (c *AuditController) getEndpoint() string {
+	return "/v1/audit"
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type AuthController struct {
+	sev.Controller
+	sev *sev.Sev
+
+	Prefix string
+}
+
+func (c *AuthController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/login", c.login)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/session", interceptor.Permission(dto.PERMISSION_READ), c.logout)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/me", interceptor.Permission(dto.PERMISSION_READ), c.me)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/keys", interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.listApiKeys)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/keys", interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.addApiKey)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/keys/:uuid", interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.revokeApiKey)
+}
+
+// @Summary Login
+// @Description Exchange an api key or username and password for a session token, eg. for the web ui
+// @Tags auth
+// @Accept json
+// @Param request body dto.Login true "api key or username and password"
+// @Produce json
+// @Success 200 {object} dto.Session
+// @Router /auth/login [post]
+func (c *AuthController) login(gin *gin.Context) {
+	login := &dto.Login{}
+	if !c.sev.Validate().Bind(gin, login) {
+		return
+	}
+
+	session, err := service.AuthService().Login(login)
+	if err != nil {
+		gin.JSON(401, exceptions.HttpUnauthorized(err, "https://docs.ffmate.io/docs/authentication#login"))
+		return
+	}
+
+	gin.JSON(200, session)
+}
+
+// @Summary Logout
+// @Description Invalidate the session token used for this request
+// @Tags auth
+// @Produce json
+// @Success 204
+// @Router /auth/session [delete]
+func (c *AuthController) logout(gin *gin.Context) {
+	token := strings.TrimPrefix(gin.GetHeader("Authorization"), "Bearer ")
+	if err := service.AuthService().Logout(token); err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#logout"))
+		return
+	}
+
+	gin.AbortWithStatus(204)
+}
+
+// @Summary Get the current caller
+// @Description Get the name and scopes of the api key or session used for this request
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.Auth
+// @Router /auth/me [get]
+func (c *AuthController) me(gin *gin.Context) {
+	auth := currentAuth(gin)
+	if auth == nil {
+		gin.JSON(400, exceptions.HttpBadRequest(errors.New("authentication is disabled"), "https://docs.ffmate.io/docs/authentication"))
+		return
+	}
+
+	gin.JSON(200, auth)
+}
+
+// @Summary List all api keys
+// @Description List all api keys, the keys themselves are never returned
+// @Tags auth
+// @Produce json
+// @Success 200 {object} []dto.ApiKey
+// @Router /auth/keys [get]
+func (c *AuthController) listApiKeys(gin *gin.Context) {
+	apiKeys, err := service.AuthService().ListApiKeys()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#api-keys"))
+		return
+	}
+
+	// Transform each api key to its DTO
+	var apiKeyDTOs = []dto.ApiKey{}
+	for _, apiKey := range *apiKeys {
+		apiKeyDTOs = append(apiKeyDTOs, *apiKey.ToDto())
+	}
+
+	gin.JSON(200, apiKeyDTOs)
+}
+
+// @Summary Add a new api key
+// @Description Add a new api key, the key is only part of this response
+// @Tags auth
+// @Accept json
+// @Param request body dto.NewApiKey true "new api key"
+// @Produce json
+// @Success 200 {object} dto.CreatedApiKey
+// @Router /auth/keys [post]
+func (c *AuthController) addApiKey(gin *gin.Context) {
+	newApiKey := &dto.NewApiKey{}
+	if !c.sev.Validate().Bind(gin, newApiKey) {
+		return
+	}
+	if auth := currentAuth(gin); auth != nil {
+		newApiKey.User = auth.User
+	}
+
+	key, apiKey, err := service.AuthService().CreateApiKey(newApiKey)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#api-keys"))
+		return
+	}
+
+	gin.JSON(200, &dto.CreatedApiKey{ApiKey: *apiKey.ToDto(), Key: key})
+}
+
+// @Summary Revoke an api key
+// @Description Revoke an api key and all its sessions
+// @Tags auth
+// @Param uuid path string true "the api keys uuid"
+// @Produce json
+// @Success 204
+// @Router /auth/keys/{uuid} [delete]
+func (c *AuthController) revokeApiKey(gin *gin.Context) {
+	if err := service.AuthService().RevokeApiKey(gin.Param("uuid")); err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#api-keys"))
+		return
+	}
+
+	gin.AbortWithStatus(204)
+}
+
+// currentAuth returns the authenticated caller, nil if authentication is disabled
+func currentAuth(gin *gin.Context) *dto.Auth {
+	if auth, ok := gin.Get("auth"); ok {
+		return auth.(*dto.Auth)
+	}
+	return nil
+}
+
+// actor identifies the caller for createdBy and updatedBy fields and the audit log
+func actor(gin *gin.Context) *dto.Actor {
+	a := &dto.Actor{Ip: gin.ClientIP()}
+	if auth := currentAuth(gin); auth != nil {
+		a.Name = auth.Actor()
+	}
+	return a
+}
+
+func (c *AuthController) GetName() string {
+	return "auth"
+}
+
+func (c *AuthController) getEndpoint() string {
+	return "/v1/auth"
+}
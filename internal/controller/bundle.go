@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type BundleController struct {
+	sev.Controller
+	sev *sev.Sev
+
+	Prefix string
+}
+
+func (c *BundleController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), c.exportBundle)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/import", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), interceptor.Permission(dto.PERMISSION_WATCHFOLDER_WRITE), interceptor.Permission(dto.PERMISSION_WEBHOOK_WRITE), c.importBundle)
+}
+
+// @Summary Export a bundle
+// @Description Export all presets, watchfolders and webhooks as bundle, secrets are left out
+// @Tags bundle
+// @Param format query string false "yaml (default) or json"
+// @Produce json
+// @Success 200 {object} dto.Bundle
+// @Router /bundle [get]
+func (c *BundleController) exportBundle(gin *gin.Context) {
+	format := gin.DefaultQuery("format", "yaml")
+	bundle, err := service.BundleService().Export()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/bundles"))
+		return
+	}
+	b, err := service.MarshalBundle(bundle, format)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/bundles"))
+		return
+	}
+
+	gin.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"ffmate-bundle.%s\"", format))
+	gin.Data(200, "application/"+format, b)
+}
+
+// @Summary Import a bundle
+// @Description Import the presets, watchfolders and webhooks of a yaml or json bundle
+// @Tags bundle
+// @Accept json
+// @Param conflict query string false "how to handle existing resources: skip (default), overwrite or rename"
+// @Param dryRun query bool false "only report what would be imported"
+// @Param request body dto.Bundle true "the bundle"
+// @Produce json
+// @Success 200 {object} dto.BundleImport
+// @Router /bundle/import [post]
+func (c *BundleController) importBundle(gin *gin.Context) {
+	dryRun, _ := strconv.ParseBool(gin.DefaultQuery("dryRun", "false"))
+	data, err := gin.GetRawData()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/bundles"))
+		return
+	}
+	bundle, err := service.ParseBundle(data)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/bundles"))
+		return
+	}
+
+	result, err := service.BundleService().Import(bundle, dto.BundleConflict(gin.Query("conflict")), dryRun, actor(gin))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/bundles"))
+		return
+	}
+
+	gin.JSON(200, result)
+}
+
+func (c *BundleController) GetName() string {
+	return "bundle"
+}
+
+func (c *BundleController) getEndpoint() string {
+	return "/v1/bundle"
+}
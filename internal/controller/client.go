@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
 	"github.com/welovemedia/ffmate/sev"
 )
 
@@ -18,7 +19,7 @@ type ClientController struct {
 
 func (c *ClientController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().GET(c.Prefix+c.getEndpoint(), c.getClient)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), c.getClient)
 }
 
 // @Summary Get Client info
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/yosev/debugo"
 )
@@ -14,8 +16,8 @@ type DebugController struct {
 
 func (v *DebugController) Setup(s *sev.Sev) {
 	v.sev = s
-	s.Gin().PATCH(v.Prefix+v.getEndpoint()+"/namespace/:namespaces", v.setDebug)
-	s.Gin().DELETE(v.Prefix+v.getEndpoint()+"/namespace", v.disableDebug)
+	s.Gin().PATCH(v.Prefix+v.getEndpoint()+"/namespace/:namespaces", interceptor.Permission(dto.PERMISSION_DEBUG_WRITE), v.setDebug)
+	s.Gin().DELETE(v.Prefix+v.getEndpoint()+"/namespace", interceptor.Permission(dto.PERMISSION_DEBUG_WRITE), v.disableDebug)
 }
 
 // @Summary Set debug namespace(s)
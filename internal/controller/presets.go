@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/welovemedia/ffmate/internal/dto"
@@ -20,11 +21,18 @@ type PresetController struct {
 
 func (c *PresetController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", c.deletePreset)
-	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addPreset)
-	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", c.updatePreset)
-	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listPresets)
-	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", c.getPreset)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), c.deletePreset)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_PRESET_WRITE), c.addPreset)
+	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), c.updatePreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.listPresets)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/library", interceptor.Permission(dto.PERMISSION_READ), c.listLibrary)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/library/:id/install", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), c.installLibraryPreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_READ), c.getPreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/effective", interceptor.Permission(dto.PERMISSION_READ), c.getEffectivePreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/revisions", interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.listRevisions)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/revisions/:revision", interceptor.Permission(dto.PERMISSION_READ), c.getRevision)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/diff", interceptor.Permission(dto.PERMISSION_READ), c.diffRevisions)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/:uuid/revisions/:revision/rollback", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), c.rollbackPreset)
 }
 
 // @Summary Delete a preset
@@ -36,7 +44,7 @@ func (c *PresetController) Setup(s *sev.Sev) {
 // @Router /presets/{uuid} [delete]
 func (c *PresetController) deletePreset(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	err := service.PresetService().DeletePreset(uuid)
+	err := service.PresetService().DeletePreset(uuid, actor(gin))
 
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#deleting-a-preset"))
@@ -84,7 +92,7 @@ func (c *PresetController) addPreset(gin *gin.Context) {
 		return
 	}
 
-	preset, err := service.PresetService().NewPreset(newPreset)
+	preset, err := service.PresetService().NewPreset(newPreset, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#creating-a-preset"))
 		return
@@ -126,7 +134,7 @@ func (c *PresetController) updatePreset(gin *gin.Context) {
 		return
 	}
 
-	preset, err := service.PresetService().UpdatePreset(uuid, newPreset)
+	preset, err := service.PresetService().UpdatePreset(uuid, newPreset, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#updating-a-preset"))
 		return
@@ -135,6 +143,171 @@ func (c *PresetController) updatePreset(gin *gin.Context) {
 	gin.JSON(200, preset.ToDto())
 }
 
+// @Summary List the preset library
+// @Description	List the presets shipped with ffmate and whether they are installed or can be updated
+// @Tags presets
+// @Produce json
+// @Success 200 {object} []dto.LibraryPreset
+// @Router /presets/library [get]
+func (c *PresetController) listLibrary(gin *gin.Context) {
+	presets, err := service.PresetService().Library()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, presets)
+}
+
+// @Summary Install a library preset
+// @Description	Install a preset of the library or update the installed preset to the current version of the library
+// @Tags presets
+// @Param id path string true "the id of the library preset"
+// @Produce json
+// @Success 200 {object} dto.Preset
+// @Router /presets/library/{id}/install [post]
+func (c *PresetController) installLibraryPreset(gin *gin.Context) {
+	preset, err := service.PresetService().InstallLibraryPreset(gin.Param("id"), actor(gin))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, preset.ToDto())
+}
+
+// @Summary Get the effective preset
+// @Description	Get a preset with everything it inherits from its base presets applied
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Produce json
+// @Success 200 {object} dto.Preset
+// @Router /presets/{uuid}/effective [get]
+func (c *PresetController) getEffectivePreset(gin *gin.Context) {
+	preset, err := service.PresetService().EffectivePreset(gin.Param("uuid"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-inheritance"))
+		return
+	}
+
+	gin.JSON(200, preset.ToDto())
+}
+
+// @Summary List preset revisions
+// @Description	List all revisions of a preset, newest first
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Param page query int false "the page of a pagination request (min 0)"
+// @Param perPage query int false "the amount of results of a pagination request (min 1; max: 100)"
+// @Produce json
+// @Success 200 {object} []dto.PresetRevision
+// @Router /presets/{uuid}/revisions [get]
+func (c *PresetController) listRevisions(gin *gin.Context) {
+	revisions, total, err := service.PresetService().ListRevisions(gin.Param("uuid"), gin.GetInt("page"), gin.GetInt("perPage"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	gin.Header("X-Total", fmt.Sprintf("%d", total))
+
+	// Transform each revision to its DTO
+	var revisionDTOs = []dto.PresetRevision{}
+	for _, revision := range *revisions {
+		revisionDTOs = append(revisionDTOs, *revision.ToDto())
+	}
+
+	gin.JSON(200, revisionDTOs)
+}
+
+// @Summary Get a preset revision
+// @Description	Get a single revision of a preset
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Param revision path int true "the revision"
+// @Produce json
+// @Success 200 {object} dto.PresetRevision
+// @Router /presets/{uuid}/revisions/{revision} [get]
+func (c *PresetController) getRevision(gin *gin.Context) {
+	revision, err := parseRevision(gin.Param("revision"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	r, err := service.PresetService().FindRevision(gin.Param("uuid"), revision)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	gin.JSON(200, r.ToDto())
+}
+
+// @Summary Diff preset revisions
+// @Description	List the fields that changed between two revisions of a preset
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Param from query int true "the older revision"
+// @Param to query int false "the newer revision (default: current)"
+// @Produce json
+// @Success 200 {object} dto.PresetRevisionDiff
+// @Router /presets/{uuid}/diff [get]
+func (c *PresetController) diffRevisions(gin *gin.Context) {
+	from, err := parseRevision(gin.Query("from"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+	var to uint
+	if gin.Query("to") != "" {
+		if to, err = parseRevision(gin.Query("to")); err != nil {
+			gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+			return
+		}
+	}
+
+	diff, err := service.PresetService().DiffRevisions(gin.Param("uuid"), from, to)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	gin.JSON(200, diff)
+}
+
+// @Summary Roll back a preset
+// @Description	Restore an earlier revision of a preset, it is stored as a new revision
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Param revision path int true "the revision to restore"
+// @Produce json
+// @Success 200 {object} dto.Preset
+// @Router /presets/{uuid}/revisions/{revision}/rollback [post]
+func (c *PresetController) rollbackPreset(gin *gin.Context) {
+	revision, err := parseRevision(gin.Param("revision"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	preset, err := service.PresetService().RollbackPreset(gin.Param("uuid"), revision, actor(gin))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-revisions"))
+		return
+	}
+
+	gin.JSON(200, preset.ToDto())
+}
+
+func parseRevision(value string) (uint, error) {
+	revision, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || revision == 0 {
+		return 0, fmt.Errorf("invalid revision '%s'", value)
+	}
+	return uint(revision), nil
+}
+
 func (c *PresetController) GetName() string {
 	return "preset"
 }
@@ -30,7 +30,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 	}
 
 	// Auto migrate models
-	err = db.AutoMigrate(&model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -71,11 +71,11 @@ func TestPresetController(t *testing.T) {
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.PRESET_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.PRESET_DELETED,
 		Url:   webhookURL,
-	})
+	}, nil)
 
 	controller := &PresetController{
 		Prefix: "",
@@ -147,7 +147,7 @@ func TestPresetController(t *testing.T) {
 		preset, err := service.PresetService().NewPreset(&dto.NewPreset{
 			Name:    "To Delete",
 			Command: "test",
-		})
+		}, nil)
 		if err != nil {
 			t.Fatalf("Failed to create preset: %v", err)
 		}
@@ -167,4 +167,27 @@ func TestPresetController(t *testing.T) {
 			t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
 		}
 	})
+	t.Run("Rollback preset", func(t *testing.T) {
+		preset, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "To Roll Back", Command: "-c:v libx264"}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create preset: %v", err)
+		}
+		waitForWebhook(t, webhookCalls, dto.PRESET_CREATED)
+		if _, err := service.PresetService().UpdatePreset(preset.Uuid, &dto.NewPreset{Name: "To Roll Back", Command: "-c:v libx265"}, nil); err != nil {
+			t.Fatalf("Failed to update preset: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/presets/"+preset.Uuid+"/revisions/1/rollback", nil)
+		s.Gin().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response dto.Preset
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Command != "-c:v libx264" || response.Revision != 3 {
+			t.Errorf("Expected revision 1 to be restored as revision 3, got %+v", response)
+		}
+	})
 }
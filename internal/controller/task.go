@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/welovemedia/ffmate/internal/dto"
@@ -11,6 +13,9 @@ import (
 	"github.com/welovemedia/ffmate/sev/exceptions"
 )
 
+// dryRunTimeout bounds the ffprobe runs a dry run may trigger
+const dryRunTimeout = 30 * time.Second
+
 type TaskController struct {
 	sev.Controller
 	sev    *sev.Sev
@@ -19,14 +24,15 @@ type TaskController struct {
 
 func (c *TaskController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, interceptor.TaskStatus, c.listTasks)
-	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addTask)
-	s.Gin().POST(c.Prefix+c.getEndpoint()+"/batch", c.addTasks)
-	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", c.getTask)
-	s.Gin().GET(c.Prefix+c.getEndpoint()+"/batch/:uuid", interceptor.PageLimit, c.getTasks)
-	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", c.deleteTask)
-	s.Gin().PATCH(c.Prefix+c.getEndpoint()+"/:uuid/cancel", c.cancelTask)
-	s.Gin().PATCH(c.Prefix+c.getEndpoint()+"/:uuid/restart", c.restartTask)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, interceptor.TaskStatus, c.listTasks)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_TASK_SUBMIT), c.addTask)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/batch", interceptor.Permission(dto.PERMISSION_TASK_SUBMIT), c.addTasks)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/dry-run", interceptor.Permission(dto.PERMISSION_TASK_SUBMIT), c.dryRunTask)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_READ), c.getTask)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/batch/:uuid", interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.getTasks)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_TASK_DELETE), c.deleteTask)
+	s.Gin().PATCH(c.Prefix+c.getEndpoint()+"/:uuid/cancel", interceptor.Permission(dto.PERMISSION_TASK_CANCEL), c.cancelTask)
+	s.Gin().PATCH(c.Prefix+c.getEndpoint()+"/:uuid/restart", interceptor.Permission(dto.PERMISSION_TASK_CANCEL), c.restartTask)
 }
 
 // @Summary List all tasks
@@ -64,7 +70,7 @@ func (c *TaskController) listTasks(gin *gin.Context) {
 // @Router /tasks/{uuid} [delete]
 func (c *TaskController) deleteTask(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	err := service.TaskService().DeleteTask(uuid)
+	err := service.TaskService().DeleteTask(uuid, actor(gin))
 
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/tasks#deleting-a-task"))
@@ -89,8 +95,9 @@ func (c *TaskController) addTasks(gin *gin.Context) {
 	}
 
 	// bind and validation in a single step throws a nil error, so we separate those tasks
-	for _, t := range *newTasks {
-		c.sev.Validate().ValidateOnly(gin, &t)
+	for i := range *newTasks {
+		c.sev.Validate().ValidateOnly(gin, &(*newTasks)[i])
+		(*newTasks)[i].CreatedBy = actor(gin).Name
 	}
 
 	tasks, err := service.TaskService().NewTasks(newTasks)
@@ -122,6 +129,7 @@ func (c *TaskController) addTask(gin *gin.Context) {
 		return
 	}
 
+	newTask.CreatedBy = actor(gin).Name
 	task, err := service.TaskService().NewTask(newTask, "", "api")
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/tasks#creating-a-task"))
@@ -131,6 +139,33 @@ func (c *TaskController) addTask(gin *gin.Context) {
 	gin.JSON(200, task.ToDto())
 }
 
+// @Summary Dry run a task
+// @Description	Resolve a new task including its preset and wildcards and run all checks without creating it
+// @Tags tasks
+// @Accept json
+// @Param request body dto.NewTask true "new task"
+// @Produce json
+// @Success 200 {object} dto.DryRunTask
+// @Router /tasks/dry-run [post]
+func (c *TaskController) dryRunTask(gin *gin.Context) {
+	newTask := &dto.NewTask{}
+	if !c.sev.Validate().Bind(gin, newTask) {
+		return
+	}
+
+	newTask.CreatedBy = actor(gin).Name
+	// templates may probe the input file, which must not outlive the request
+	ctx, cancel := context.WithTimeout(gin.Request.Context(), dryRunTimeout)
+	defer cancel()
+	task, err := service.TaskService().DryRun(ctx, newTask, "api")
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/tasks#dry-running-a-task"))
+		return
+	}
+
+	gin.JSON(200, task)
+}
+
 // @Summary Get single task
 // @Description	Get a single task by its uuid
 // @Tags tasks
@@ -184,7 +219,7 @@ func (c *TaskController) getTasks(gin *gin.Context) {
 // @Router /tasks/{uuid}/cancel [patch]
 func (c *TaskController) cancelTask(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	task, err := service.TaskService().CancelTask(uuid)
+	task, err := service.TaskService().CancelTask(uuid, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/tasks#canceling-a-task"))
 		return
@@ -202,7 +237,7 @@ func (c *TaskController) cancelTask(gin *gin.Context) {
 // @Router /tasks/{uuid}/restart [patch]
 func (c *TaskController) restartTask(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	task, err := service.TaskService().RestartTask(uuid)
+	task, err := service.TaskService().RestartTask(uuid, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/tasks#restarting-a-task"))
 		return
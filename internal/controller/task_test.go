@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/metrics"
@@ -23,7 +26,7 @@ func setupTaskTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Task{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Task{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -59,15 +62,15 @@ func TestTaskController(t *testing.T) {
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.TASK_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.TASK_DELETED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.BATCH_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 
 	controller := &TaskController{
 		Prefix: "",
@@ -135,6 +138,127 @@ func TestTaskController(t *testing.T) {
 		}
 	})
 
+	t.Run("Dry run task", func(t *testing.T) {
+		_, before, _ := service.TaskService().ListTasks(0, 1, "")
+		metadata := dto.InterfaceMap{"show": "Late Night"}
+		newTask := dto.NewTask{
+			InputFile:  "/test/input.mp4",
+			OutputFile: "/test/${metadata.show | slug}.mp4",
+			Command:    "-i ${INPUT_FILE} -metadata title=${metadata.show | upper | quote} ${OUTPUT_FILE}",
+			Metadata:   &metadata,
+		}
+
+		body, _ := json.Marshal(newTask)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/tasks/dry-run", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		s.Gin().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response dto.DryRunTask
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.OutputFile.Resolved != "/test/late-night.mp4" {
+			t.Errorf("Expected resolved output file, got %s", response.OutputFile.Resolved)
+		}
+		want := []string{"-i", "/test/input.mp4", "-metadata", "title=LATE NIGHT", "/test/late-night.mp4"}
+		if strings.Join(response.Arguments, "|") != strings.Join(want, "|") {
+			t.Errorf("Expected arguments %v, got %v", want, response.Arguments)
+		}
+
+		_, after, _ := service.TaskService().ListTasks(0, 1, "")
+		if after != before {
+			t.Errorf("Expected dry run not to create a task")
+		}
+
+		newTask.Metadata = nil
+		body, _ = json.Marshal(newTask)
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest("POST", "/v1/tasks/dry-run", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		s.Gin().ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "has no value") {
+			t.Errorf("Expected missing metadata to be reported, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Dry run checks input before probing", func(t *testing.T) {
+		viper.Set("inputRoots", []string{t.TempDir()})
+		config.Init()
+		defer func() {
+			viper.Set("inputRoots", []string{})
+			config.Init()
+		}()
+
+		metadata := dto.InterfaceMap{"path": "/etc/passwd"}
+		body, _ := json.Marshal(dto.NewTask{
+			InputFile:  "${metadata.path}",
+			OutputFile: "/test/output.mp4",
+			Command:    "-i ${INPUT_FILE} -t ${probe.duration} ${OUTPUT_FILE}",
+			Metadata:   &metadata,
+		})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/tasks/dry-run", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		s.Gin().ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "outside the allowed directories") {
+			t.Errorf("Expected input to be rejected before probing, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Preset parameters", func(t *testing.T) {
+		min, max := 0.0, 51.0
+		preset, err := service.PresetService().NewPreset(&dto.NewPreset{
+			Name:    "Parameterized",
+			Command: "-i ${INPUT_FILE} -crf ${param.crf} -preset ${param.speed} ${OUTPUT_FILE}",
+			Parameters: dto.PresetParameters{
+				"crf":   {Type: dto.PARAMETER_NUMBER, Min: &min, Max: &max, Default: 23},
+				"speed": {Type: dto.PARAMETER_ENUM, Values: []string{"fast", "slow"}},
+			},
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create preset: %v", err)
+		}
+
+		dryRun := func(params dto.InterfaceMap) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(dto.NewTask{Preset: preset.Uuid, InputFile: "/test/input.mp4", OutputFile: "/test/output.mp4", Params: &params})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/v1/tasks/dry-run", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			s.Gin().ServeHTTP(w, req)
+			return w
+		}
+
+		w := dryRun(dto.InterfaceMap{"speed": "slow"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response dto.DryRunTask
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Command.Resolved != "-i \"/test/input.mp4\" -crf 23 -preset slow \"/test/output.mp4\"" {
+			t.Errorf("Expected parameters to be resolved, got %s", response.Command.Resolved)
+		}
+		if response.Params == nil || (*response.Params)["crf"] != float64(23) {
+			t.Errorf("Expected default parameter in task, got %v", response.Params)
+		}
+
+		for want, params := range map[string]dto.InterfaceMap{
+			"parameter 'speed' is required":      {},
+			"parameter 'crf' must be at most 51": {"speed": "fast", "crf": 60},
+			"parameter 'speed' must be one of":   {"speed": "medium"},
+			"unknown parameter 'tune'":           {"speed": "fast", "tune": "film"},
+		} {
+			if w := dryRun(params); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+				t.Errorf("Expected '%s', got %d: %s", want, w.Code, w.Body.String())
+			}
+		}
+	})
+
 	t.Run("List tasks", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req := httptest.NewRequest("GET", "/v1/tasks?page=0&perPage=10", nil)
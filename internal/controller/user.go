@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type UserController struct {
+	sev.Controller
+	sev *sev.Sev
+
+	Prefix string
+}
+
+func (c *UserController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.listUsers)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.addUser)
+	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.updateUser)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_USER_MANAGE), c.deleteUser)
+}
+
+// @Summary List all users
+// @Description List all users and their roles
+// @Tags users
+// @Produce json
+// @Success 200 {object} []dto.User
+// @Router /users [get]
+func (c *UserController) listUsers(gin *gin.Context) {
+	users, err := service.AuthService().ListUsers()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#users"))
+		return
+	}
+
+	// Transform each user to its DTO
+	var userDTOs = []dto.User{}
+	for _, user := range *users {
+		userDTOs = append(userDTOs, *user.ToDto())
+	}
+
+	gin.JSON(200, userDTOs)
+}
+
+// @Summary Add a new user
+// @Description Add a new user with a role (viewer, operator or admin)
+// @Tags users
+// @Accept json
+// @Param request body dto.NewUser true "new user"
+// @Produce json
+// @Success 200 {object} dto.User
+// @Router /users [post]
+func (c *UserController) addUser(gin *gin.Context) {
+	newUser := &dto.NewUser{}
+	if !c.sev.Validate().Bind(gin, newUser) {
+		return
+	}
+
+	user, err := service.AuthService().CreateUser(newUser)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#users"))
+		return
+	}
+
+	gin.JSON(200, user.ToDto())
+}
+
+// @Summary Update a user
+// @Description Update the role and optionally the password of a user
+// @Tags users
+// @Accept json
+// @Param uuid path string true "the users uuid"
+// @Param request body dto.NewUser true "updated user"
+// @Produce json
+// @Success 200 {object} dto.User
+// @Router /users/{uuid} [put]
+func (c *UserController) updateUser(gin *gin.Context) {
+	newUser := &dto.NewUser{}
+	if !c.sev.Validate().Bind(gin, newUser) {
+		return
+	}
+
+	user, err := service.AuthService().UpdateUser(gin.Param("uuid"), newUser)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#users"))
+		return
+	}
+
+	gin.JSON(200, user.ToDto())
+}
+
+// @Summary Delete a user
+// @Description Delete a user together with its api keys and sessions
+// @Tags users
+// @Param uuid path string true "the users uuid"
+// @Produce json
+// @Success 204
+// @Router /users/{uuid} [delete]
+func (c *UserController) deleteUser(gin *gin.Context) {
+	if err := service.AuthService().DeleteUser(gin.Param("uuid")); err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/authentication#users"))
+		return
+	}
+
+	gin.AbortWithStatus(204)
+}
+
+func (c *UserController) GetName() string {
+	return "user"
+}
+
+func (c *UserController) getEndpoint() string {
+	return "/v1/users"
+}
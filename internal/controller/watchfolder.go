@@ -20,11 +20,11 @@ type WatchfolderController struct {
 
 func (c *WatchfolderController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", c.deleteWatchfolder)
-	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", c.updateWatchfolder)
-	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addWatchfolder)
-	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listWatchfolders)
-	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", c.getWatchfolder)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_WATCHFOLDER_WRITE), c.deleteWatchfolder)
+	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_WATCHFOLDER_WRITE), c.updateWatchfolder)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_WATCHFOLDER_WRITE), c.addWatchfolder)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.listWatchfolders)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_READ), c.getWatchfolder)
 }
 
 // @Summary Get single watchfolder
@@ -54,7 +54,7 @@ func (c *WatchfolderController) getWatchfolder(gin *gin.Context) {
 // @Router /watchfolders/{uuid} [delete]
 func (c *WatchfolderController) deleteWatchfolder(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	err := service.WatchfolderService().DeleteWatchfolder(uuid)
+	err := service.WatchfolderService().DeleteWatchfolder(uuid, actor(gin))
 
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#deleting-a-watchfolder"))
@@ -102,7 +102,7 @@ func (c *WatchfolderController) addWatchfolder(gin *gin.Context) {
 		return
 	}
 
-	watchfolder, err := service.WatchfolderService().NewWatchfolder(newWatchfolder)
+	watchfolder, err := service.WatchfolderService().NewWatchfolder(newWatchfolder, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#creating-a-watchfolder"))
 		return
@@ -126,7 +126,7 @@ func (c *WatchfolderController) updateWatchfolder(gin *gin.Context) {
 		return
 	}
 
-	watchfolder, err := service.WatchfolderService().UpdateWatchfolder(uuid, newWatchfolder)
+	watchfolder, err := service.WatchfolderService().UpdateWatchfolder(uuid, newWatchfolder, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#updating-a-watchfolder"))
 		return
@@ -23,7 +23,7 @@ func setupWatchfolderTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -59,21 +59,21 @@ func TestWatchfolderController(t *testing.T) {
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.PRESET_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.WATCHFOLDER_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.WATCHFOLDER_DELETED,
 		Url:   webhookURL,
-	})
+	}, nil)
 
 	// Create a preset first that we'll need for the watchfolder and wait for its webhook
 	preset, err := service.PresetService().NewPreset(&dto.NewPreset{
 		Name:    "Test Preset",
 		Command: "test command",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Failed to create preset: %v", err)
 	}
@@ -20,9 +20,11 @@ type WebhookController struct {
 
 func (c *WebhookController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", c.deleteWebhook)
-	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addWebhook)
-	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listWebhooks)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid", interceptor.Permission(dto.PERMISSION_WEBHOOK_WRITE), c.deleteWebhook)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_WEBHOOK_WRITE), c.addWebhook)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.listWebhooks)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/deliveries", interceptor.Permission(dto.PERMISSION_READ), interceptor.PageLimit, c.listDeliveries)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/:uuid/deliveries/:delivery/redeliver", interceptor.Permission(dto.PERMISSION_WEBHOOK_WRITE), c.redeliver)
 }
 
 // @Summary Delete a webhook
@@ -34,7 +36,7 @@ func (c *WebhookController) Setup(s *sev.Sev) {
 // @Router /webhooks/{uuid} [delete]
 func (c *WebhookController) deleteWebhook(gin *gin.Context) {
 	uuid := gin.Param("uuid")
-	err := service.WebhookService().DeleteWebhook(uuid)
+	err := service.WebhookService().DeleteWebhook(uuid, actor(gin))
 
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/webhooks#deleting-a-webhook"))
@@ -82,7 +84,7 @@ func (c *WebhookController) addWebhook(gin *gin.Context) {
 		return
 	}
 
-	webhook, err := service.WebhookService().NewWebhook(newWebhook)
+	webhook, err := service.WebhookService().NewWebhook(newWebhook, actor(gin))
 	if err != nil {
 		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/webhooks#creating-a-webhook"))
 		return
@@ -91,6 +93,49 @@ func (c *WebhookController) addWebhook(gin *gin.Context) {
 	gin.JSON(200, webhook.ToDto())
 }
 
+// @Summary List deliveries of a webhook
+// @Description List the delivery log of a webhook, newest first
+// @Tags webhooks
+// @Param uuid path string true "the webhooks uuid"
+// @Produce json
+// @Success 200 {object} []dto.WebhookDelivery
+// @Router /webhooks/{uuid}/deliveries [get]
+func (c *WebhookController) listDeliveries(gin *gin.Context) {
+	deliveries, total, err := service.WebhookService().ListDeliveries(gin.Param("uuid"), gin.GetInt("page"), gin.GetInt("perPage"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/webhooks#listing-deliveries"))
+		return
+	}
+
+	gin.Header("X-Total", fmt.Sprintf("%d", total))
+
+	// Transform each delivery to its DTO
+	var deliveryDTOs = []dto.WebhookDelivery{}
+	for _, delivery := range *deliveries {
+		deliveryDTOs = append(deliveryDTOs, *delivery.ToDto())
+	}
+
+	gin.JSON(200, deliveryDTOs)
+}
+
+// @Summary Redeliver a webhook delivery
+// @Description Send the payload of a previous delivery again
+// @Tags webhooks
+// @Param uuid path string true "the webhooks uuid"
+// @Param delivery path string true "the deliveries uuid"
+// @Produce json
+// @Success 200 {object} dto.WebhookDelivery
+// @Router /webhooks/{uuid}/deliveries/{delivery}/redeliver [post]
+func (c *WebhookController) redeliver(gin *gin.Context) {
+	delivery, err := service.WebhookService().Redeliver(gin.Param("uuid"), gin.Param("delivery"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/webhooks#redelivering-a-webhook"))
+		return
+	}
+
+	gin.JSON(200, delivery.ToDto())
+}
+
 func (c *WebhookController) GetName() string {
 	return "webhook"
 }
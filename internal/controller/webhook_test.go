@@ -55,7 +55,7 @@ func setupWebhookTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Webhook{})
+	err = db.AutoMigrate(&model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -85,17 +85,17 @@ func TestWebhookController(t *testing.T) {
 	defer sqlDB.Close()
 
 	// Setup webhook server and expected calls
-	webhookURL, webhookCalls := setupWebhookServer(t, 2) // Expect: created, deleted
+	webhookURL, webhookCalls := setupWebhookServer(t, 3) // Expect: created, redelivered, deleted
 
 	// Setup webhooks for webhook events
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.WEBHOOK_CREATED,
 		Url:   webhookURL,
-	})
+	}, nil)
 	service.WebhookService().NewWebhook(&dto.NewWebhook{
 		Event: dto.WEBHOOK_DELETED,
 		Url:   webhookURL,
-	})
+	}, nil)
 
 	// Wait for the webhook listener creation events (as we create two webhook lsiteners)
 	waitForWebhook(t, webhookCalls, dto.WEBHOOK_CREATED)
@@ -155,6 +155,84 @@ func TestWebhookController(t *testing.T) {
 		}
 	})
 
+	t.Run("List and redeliver deliveries", func(t *testing.T) {
+		webhooks, _, _ := service.WebhookService().ListWebhooks(0, 10)
+		var webhookUuid string
+		for _, webhook := range *webhooks {
+			if webhook.Event == dto.WEBHOOK_CREATED {
+				webhookUuid = webhook.Uuid
+			}
+		}
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/v1/webhooks/"+webhookUuid+"/deliveries", nil)
+		s.Gin().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var deliveries []dto.WebhookDelivery
+		if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(deliveries) < 1 {
+			t.Fatal("Expected at least one delivery")
+		}
+		if deliveries[0].Event != dto.WEBHOOK_CREATED {
+			t.Errorf("Expected event %s, got %s", dto.WEBHOOK_CREATED, deliveries[0].Event)
+		}
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest("POST", "/v1/webhooks/"+webhookUuid+"/deliveries/"+deliveries[0].Uuid+"/redeliver", nil)
+		s.Gin().ServeHTTP(w, req)
+		waitForWebhook(t, webhookCalls, dto.WEBHOOK_CREATED)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var delivery dto.WebhookDelivery
+		json.Unmarshal(w.Body.Bytes(), &delivery)
+		if delivery.Uuid == deliveries[0].Uuid || delivery.Payload != deliveries[0].Payload {
+			t.Errorf("Expected a new delivery with the same payload, got %+v", delivery)
+		}
+	})
+
+	t.Run("List deliveries of plain text templates", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		webhook, err := service.WebhookService().NewWebhook(&dto.NewWebhook{
+			Event:       dto.PRESET_CREATED,
+			Url:         server.URL,
+			Template:    "preset {{ .data.name }} was created",
+			ContentType: "text/plain",
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+		waitForWebhook(t, webhookCalls, dto.WEBHOOK_CREATED)
+		service.WebhookService().Fire(dto.PRESET_CREATED, map[string]any{"name": "Proxy"})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/v1/webhooks/"+webhook.Uuid+"/deliveries", nil)
+		s.Gin().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var deliveries []dto.WebhookDelivery
+		if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(deliveries) != 1 || deliveries[0].Payload != "preset Proxy was created" {
+			t.Errorf("Expected plain text payload, got %+v", deliveries)
+		}
+	})
+
 	t.Run("Delete webhook", func(t *testing.T) {
 		webhooks, _, _ := service.WebhookService().ListWebhooks(0, 1)
 		if len(*webhooks) > 0 {
@@ -2,10 +2,15 @@ package controller
 
 import (
 	"net/http"
+	"net/url"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
 	"github.com/welovemedia/ffmate/internal/service"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/yosev/debugo"
@@ -17,7 +22,14 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(g *http.Request) bool {
-		return true
+		origins := config.Config().CorsOrigins
+		origin := g.Header.Get("Origin")
+		if origin == "" || len(origins) == 0 || slices.Contains(origins, "*") || slices.Contains(origins, origin) {
+			return true
+		}
+		// the bundled web ui connects from the same host
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == g.Host
 	},
 }
 
@@ -30,7 +42,7 @@ type WebsocketController struct {
 
 func (c *WebsocketController) Setup(s *sev.Sev) {
 	c.sev = s
-	s.Gin().GET(c.Prefix+c.getEndpoint(), c.websocket)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.Permission(dto.PERMISSION_READ), c.websocket)
 }
 
 func (c *WebsocketController) websocket(gin *gin.Context) {
@@ -44,7 +56,7 @@ func (c *WebsocketController) websocket(gin *gin.Context) {
 	defer conn.Close()
 	defer service.WebsocketService().RemoveConnection(uuid, conn)
 
-	service.WebsocketService().AddConnection(uuid, conn)
+	service.WebsocketService().AddConnection(uuid, conn, websocketAuth(gin))
 
 	debug.Debugf("new connection from %s (uuid: %s)", gin.RemoteIP(), uuid)
 
@@ -57,6 +69,18 @@ func (c *WebsocketController) websocket(gin *gin.Context) {
 	}
 }
 
+// websocketAuth returns the caller of the connection, nil if authentication is disabled
+func websocketAuth(gin *gin.Context) *dto.Auth {
+	if !config.Config().Auth {
+		return nil
+	}
+	if auth, ok := gin.Get("auth"); ok {
+		return auth.(*dto.Auth)
+	}
+	// an unknown caller is granted no permission
+	return &dto.Auth{}
+}
+
 func (c *WebsocketController) GetName() string {
 	return "webhook"
 }
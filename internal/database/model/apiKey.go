@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type ApiKey struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Uuid   string
+	User   string `gorm:"index"` // uuid of the owning user, if any
+	Name   string
+	Prefix string
+	Hash   string `gorm:"uniqueIndex"` // sha256 of the key, the key itself is never stored
+
+	Scopes []dto.Scope `gorm:"serializer:json"`
+
+	LastUsedAt int64
+}
+
+func (m *ApiKey) ToDto() *dto.ApiKey {
+	return &dto.ApiKey{
+		Uuid:   m.Uuid,
+		User:   m.User,
+		Name:   m.Name,
+		Prefix: m.Prefix,
+		Scopes: m.Scopes,
+
+		LastUsedAt: m.LastUsedAt,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (ApiKey) TableName() string {
+	return "api_key"
+}
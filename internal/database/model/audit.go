@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+type Audit struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time `gorm:"index"`
+
+	Uuid string
+
+	Resource     dto.AuditResource `gorm:"index"`
+	ResourceUuid string            `gorm:"index"`
+	Action       dto.AuditAction
+
+	Actor    string
+	SourceIp string
+
+	Before  dto.InterfaceMap  `gorm:"serializer:json"`
+	After   dto.InterfaceMap  `gorm:"serializer:json"`
+	Changes []dto.AuditChange `gorm:"serializer:json"`
+}
+
+func (m *Audit) ToDto() *dto.Audit {
+	return &dto.Audit{
+		Uuid: m.Uuid,
+
+		Resource:     m.Resource,
+		ResourceUuid: m.ResourceUuid,
+		Action:       m.Action,
+
+		Actor:    m.Actor,
+		SourceIp: m.SourceIp,
+
+		Before:  m.Before,
+		After:   m.After,
+		Changes: m.Changes,
+
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func (Audit) TableName() string {
+	return "audit"
+}
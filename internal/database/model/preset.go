@@ -16,6 +16,10 @@ type Preset struct {
 
 	Uuid string
 
+	Revision uint // the current revision, incremented with every update
+
+	Extends string `gorm:"index"` // uuid of the base preset
+
 	Command string
 	Name    string
 
@@ -26,12 +30,24 @@ type Preset struct {
 	PreProcessing  *dto.NewPrePostProcessing `gorm:"type:json"`
 	PostProcessing *dto.NewPrePostProcessing `gorm:"type:json"`
 
+	Parameters dto.PresetParameters `gorm:"type:json"`
+
 	Description string
+
+	Library        string `gorm:"index"` // id of the library preset it was installed from
+	LibraryVersion uint
+
+	Managed bool // declared in the config directory
+
+	CreatedBy string
+	UpdatedBy string
 }
 
 func (m *Preset) ToDto() *dto.Preset {
 	return &dto.Preset{
-		Uuid: m.Uuid,
+		Uuid:     m.Uuid,
+		Revision: m.Revision,
+		Extends:  m.Extends,
 
 		Command:     m.Command,
 		Name:        m.Name,
@@ -41,14 +57,57 @@ func (m *Preset) ToDto() *dto.Preset {
 
 		Priority: m.Priority,
 
-		PreProcessing:  m.PreProcessing,
-		PostProcessing: m.PostProcessing,
+		PreProcessing:  m.PreProcessing.WithoutSecrets(),
+		PostProcessing: m.PostProcessing.WithoutSecrets(),
+
+		Parameters: m.Parameters,
+
+		Library:        m.Library,
+		LibraryVersion: m.LibraryVersion,
+
+		Managed: m.Managed,
+
+		CreatedBy: m.CreatedBy,
+		UpdatedBy: m.UpdatedBy,
 
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
 	}
 }
 
+// ToRevision returns the current state of the preset as revision
+func (m *Preset) ToRevision() *PresetRevision {
+	return &PresetRevision{
+		Preset:         m.Uuid,
+		Revision:       m.Revision,
+		Extends:        m.Extends,
+		Command:        m.Command,
+		Name:           m.Name,
+		Description:    m.Description,
+		OutputFile:     m.OutputFile,
+		Priority:       m.Priority,
+		PreProcessing:  m.PreProcessing,
+		PostProcessing: m.PostProcessing,
+		Parameters:     m.Parameters,
+		CreatedBy:      m.UpdatedBy,
+	}
+}
+
+// ToNewPreset returns the preset as input, e.g. to apply its base presets
+func (m *Preset) ToNewPreset() *dto.NewPreset {
+	return &dto.NewPreset{
+		Extends:        m.Extends,
+		Command:        m.Command,
+		Name:           m.Name,
+		Description:    m.Description,
+		OutputFile:     m.OutputFile,
+		Priority:       m.Priority,
+		PreProcessing:  m.PreProcessing,
+		PostProcessing: m.PostProcessing,
+		Parameters:     m.Parameters,
+	}
+}
+
 func (Preset) TableName() string {
 	return "presets"
 }
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+type PresetRevision struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+
+	Preset   string `gorm:"index:idx_preset_revision,unique"`
+	Revision uint   `gorm:"index:idx_preset_revision,unique"`
+
+	Extends string
+
+	Command string
+	Name    string
+
+	OutputFile string
+
+	Priority uint
+
+	PreProcessing  *dto.NewPrePostProcessing `gorm:"type:json"`
+	PostProcessing *dto.NewPrePostProcessing `gorm:"type:json"`
+
+	Parameters dto.PresetParameters `gorm:"type:json"`
+
+	Description string
+
+	CreatedBy string
+}
+
+func (m *PresetRevision) ToDto() *dto.PresetRevision {
+	return &dto.PresetRevision{
+		Preset:   m.Preset,
+		Revision: m.Revision,
+		Extends:  m.Extends,
+
+		Command:     m.Command,
+		Name:        m.Name,
+		Description: m.Description,
+
+		OutputFile: m.OutputFile,
+
+		Priority: m.Priority,
+
+		PreProcessing:  m.PreProcessing.WithoutSecrets(),
+		PostProcessing: m.PostProcessing.WithoutSecrets(),
+
+		Parameters: m.Parameters,
+
+		CreatedBy: m.CreatedBy,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// ToNewPreset returns the revision as input to restore it
+func (m *PresetRevision) ToNewPreset() *dto.NewPreset {
+	return &dto.NewPreset{
+		Extends:        m.Extends,
+		Command:        m.Command,
+		Name:           m.Name,
+		Description:    m.Description,
+		OutputFile:     m.OutputFile,
+		Priority:       m.Priority,
+		PreProcessing:  m.PreProcessing,
+		PostProcessing: m.PostProcessing,
+		Parameters:     m.Parameters,
+	}
+}
+
+func (PresetRevision) TableName() string {
+	return "preset_revisions"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+type Session struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+
+	Hash   string `gorm:"uniqueIndex"` // sha256 of the session token
+	ApiKey string `gorm:"index"`       // uuid of the api key used to log in
+	User   string `gorm:"index"`       // uuid of the user logged in by password
+
+	ExpiresAt int64
+}
+
+func (Session) TableName() string {
+	return "session"
+}
@@ -17,11 +17,16 @@ type Task struct {
 
 	Name string
 
+	Preset         string
+	PresetRevision uint
+	Watchfolder    string
+
 	Command    *dto.RawResolved `gorm:"type:json"`
 	InputFile  *dto.RawResolved `gorm:"type:json"`
 	OutputFile *dto.RawResolved `gorm:"type:json"`
 
 	Metadata *dto.InterfaceMap `gorm:"serializer:json"` // Additional metadata for the task
+	Params   *dto.InterfaceMap `gorm:"serializer:json"` // parameters of the preset including their defaults
 
 	Status    dto.TaskStatus `gorm:"index"`
 	Error     string
@@ -35,6 +40,9 @@ type Task struct {
 
 	Source string
 
+	CreatedBy string
+	UpdatedBy string
+
 	Session string
 
 	StartedAt  int64
@@ -48,11 +56,16 @@ func (m *Task) ToDto() *dto.Task {
 		Name:  m.Name,
 		Batch: m.Batch,
 
+		Preset:         m.Preset,
+		PresetRevision: m.PresetRevision,
+		Watchfolder:    m.Watchfolder,
+
 		Command:    m.Command,
 		InputFile:  m.InputFile,
 		OutputFile: m.OutputFile,
 
 		Metadata: m.Metadata,
+		Params:   m.Params,
 
 		Status:    m.Status,
 		Progress:  m.Progress,
@@ -62,10 +75,13 @@ func (m *Task) ToDto() *dto.Task {
 
 		Source: m.Source,
 
+		CreatedBy: m.CreatedBy,
+		UpdatedBy: m.UpdatedBy,
+
 		Priority: m.Priority,
 
-		PreProcessing:  m.PreProcessing,
-		PostProcessing: m.PostProcessing,
+		PreProcessing:  m.PreProcessing.WithoutSecrets(),
+		PostProcessing: m.PostProcessing.WithoutSecrets(),
 
 		StartedAt:  m.StartedAt,
 		FinishedAt: m.FinishedAt,
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Uuid     string
+	Username string `gorm:"uniqueIndex"`
+	Password string // bcrypt hash
+	Role     dto.Role
+
+	LastLoginAt int64
+}
+
+func (m *User) ToDto() *dto.User {
+	return &dto.User{
+		Uuid:     m.Uuid,
+		Username: m.Username,
+		Role:     m.Role,
+
+		LastLoginAt: m.LastLoginAt,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (User) TableName() string {
+	return "users"
+}
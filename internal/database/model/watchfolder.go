@@ -1,7 +1,10 @@
 package model
 
 import (
+	"time"
+
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/schedule"
 	"gorm.io/gorm"
 )
 
@@ -17,18 +20,30 @@ type Watchfolder struct {
 	Name        string
 	Description string
 
+	Type         dto.WatchfolderType
+	S3           *dto.WatchfolderS3
 	Path         string
 	Interval     int
 	GrowthChecks int
 
-	Filter *dto.WatchfolderFilter
+	Filter   *dto.WatchfolderFilter
+	Trigger  *dto.WatchfolderTrigger
+	Schedule *dto.WatchfolderSchedule
 
 	Preset string
+	Params *dto.InterfaceMap `gorm:"serializer:json"`
 
 	Suspended bool
+	Managed   bool // declared in the config directory
 
 	Error     string
 	LastCheck int64
+
+	Health              dto.WatchfolderHealth
+	ConsecutiveFailures int
+	LastSuccessfulScan  int64
+	FilesSeen           int64
+	FilesIngested       int64
 }
 
 func (m *Watchfolder) ToDto() *dto.Watchfolder {
@@ -38,22 +53,75 @@ func (m *Watchfolder) ToDto() *dto.Watchfolder {
 		Name:        m.Name,
 		Description: m.Description,
 
+		Type:         m.Type,
+		S3:           m.s3Dto(),
 		Path:         m.Path,
 		Interval:     m.Interval,
 		GrowthChecks: m.GrowthChecks,
 
 		Preset: m.Preset,
+		Params: m.Params,
 
-		Filter: m.Filter,
+		Filter:   m.Filter,
+		Trigger:  m.Trigger,
+		Schedule: m.Schedule,
 
 		Suspended: m.Suspended,
+		Managed:   m.Managed,
 
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
 
 		Error:     m.Error,
 		LastCheck: m.LastCheck,
+
+		NextActiveAt: m.nextActiveAt(),
+
+		Health:              m.Health,
+		ConsecutiveFailures: m.ConsecutiveFailures,
+		LastSuccessfulScan:  m.LastSuccessfulScan,
+		FilesSeen:           m.FilesSeen,
+		FilesIngested:       m.FilesIngested,
+	}
+}
+
+// ToNewWatchfolder returns the watchfolder as input, e.g. to export it
+func (m *Watchfolder) ToNewWatchfolder() *dto.NewWatchfolder {
+	return &dto.NewWatchfolder{
+		Name:         m.Name,
+		Description:  m.Description,
+		Type:         m.Type,
+		S3:           m.S3,
+		Path:         m.Path,
+		Interval:     m.Interval,
+		GrowthChecks: m.GrowthChecks,
+		Filter:       m.Filter,
+		Trigger:      m.Trigger,
+		Schedule:     m.Schedule,
+		Suspended:    m.Suspended,
+		Preset:       m.Preset,
+		Params:       m.Params,
+	}
+}
+
+// s3Dto returns the s3 configuration without its secret key
+func (m *Watchfolder) s3Dto() *dto.WatchfolderS3 {
+	if m.S3 == nil {
+		return nil
+	}
+	s3 := *m.S3
+	s3.SecretKey = ""
+	return &s3
+}
+
+// nextActiveAt returns the time the watchfolder enters its schedule again or zero if it is currently active
+func (m *Watchfolder) nextActiveAt() int64 {
+	now := time.Now()
+	next, err := schedule.NextActive(m.Schedule, now)
+	if err != nil || !next.After(now) {
+		return 0
 	}
+	return next.UnixMilli()
 }
 
 func (Watchfolder) TableName() string {
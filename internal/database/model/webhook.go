@@ -16,14 +16,32 @@ type Webhook struct {
 
 	Uuid string
 
-	Event dto.WebhookEvent
-	Url   string
+	Event  dto.WebhookEvent
+	Url    string
+	Secret string
+
+	Filter  *dto.WebhookFilter `gorm:"type:json"`
+	Headers map[string]string  `gorm:"serializer:json"`
+
+	Template    string
+	ContentType string
+
+	Managed bool // declared in the config directory
 }
 
 func (m *Webhook) ToDto() *dto.Webhook {
 	return &dto.Webhook{
-		Event: m.Event,
-		Url:   m.Url,
+		Event:  m.Event,
+		Url:    m.Url,
+		Signed: m.Secret != "",
+
+		Filter:  m.Filter,
+		Headers: m.maskedHeaders(),
+
+		Template:    m.Template,
+		ContentType: m.ContentType,
+
+		Managed: m.Managed,
 
 		Uuid: m.Uuid,
 
@@ -32,6 +50,31 @@ func (m *Webhook) ToDto() *dto.Webhook {
 	}
 }
 
+// ToNewWebhook returns the webhook as input, e.g. to export it
+func (m *Webhook) ToNewWebhook() *dto.NewWebhook {
+	return &dto.NewWebhook{
+		Event:       m.Event,
+		Url:         m.Url,
+		Secret:      m.Secret,
+		Filter:      m.Filter,
+		Headers:     m.Headers,
+		Template:    m.Template,
+		ContentType: m.ContentType,
+	}
+}
+
+// header values often contain credentials and are therefore never exposed
+func (m *Webhook) maskedHeaders() map[string]string {
+	if len(m.Headers) == 0 {
+		return nil
+	}
+	headers := map[string]string{}
+	for k := range m.Headers {
+		headers[k] = "********"
+	}
+	return headers
+}
+
 func (Webhook) TableName() string {
 	return "webhook"
 }
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+type WebhookDelivery struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Uuid    string
+	Webhook string `gorm:"index"`
+	Event   dto.WebhookEvent
+	Url     string `gorm:"index"`
+
+	Status     dto.WebhookDeliveryStatus
+	Attempts   int
+	StatusCode int
+	Latency    int64
+	Response   string
+	Error      string
+
+	Payload string
+}
+
+func (m *WebhookDelivery) ToDto() *dto.WebhookDelivery {
+	return &dto.WebhookDelivery{
+		Uuid:    m.Uuid,
+		Webhook: m.Webhook,
+		Event:   m.Event,
+		Url:     m.Url,
+
+		Status:     m.Status,
+		Attempts:   m.Attempts,
+		StatusCode: m.StatusCode,
+		Latency:    m.Latency,
+		Response:   m.Response,
+		Error:      m.Error,
+
+		Payload: m.Payload,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
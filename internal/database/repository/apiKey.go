@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type ApiKey struct {
+	DB *gorm.DB
+}
+
+func (t *ApiKey) Setup() {
+	t.DB.AutoMigrate(&model.ApiKey{}, &model.Session{})
+}
+
+func (m *ApiKey) List() (*[]model.ApiKey, error) {
+	var apiKeys = &[]model.ApiKey{}
+	db := m.DB.Order("created_at DESC").Find(&apiKeys)
+	return apiKeys, db.Error
+}
+
+func (m *ApiKey) First(uuid string) (*model.ApiKey, error) {
+	var apiKey = &model.ApiKey{}
+	db := m.DB.Where("uuid = ?", uuid).Find(&apiKey)
+	return apiKey, db.Error
+}
+
+func (m *ApiKey) FirstByHash(hash string) (*model.ApiKey, error) {
+	var apiKey = &model.ApiKey{}
+	db := m.DB.Where("hash = ?", hash).Find(&apiKey)
+	return apiKey, db.Error
+}
+
+func (m *ApiKey) Create(newApiKey *dto.NewApiKey, prefix string, hash string) (*model.ApiKey, error) {
+	apiKey := &model.ApiKey{Uuid: uuid.NewString(), User: newApiKey.User, Name: newApiKey.Name, Scopes: newApiKey.Scopes, Prefix: prefix, Hash: hash}
+	db := m.DB.Create(apiKey)
+	return apiKey, db.Error
+}
+
+func (m *ApiKey) Touch(apiKey *model.ApiKey) error {
+	db := m.DB.Model(apiKey).UpdateColumn("last_used_at", time.Now().UnixMilli())
+	return db.Error
+}
+
+// Delete revokes the api key and all sessions created with it
+func (m *ApiKey) Delete(apiKey *model.ApiKey) error {
+	if db := m.DB.Where("api_key = ?", apiKey.Uuid).Delete(&model.Session{}); db.Error != nil {
+		return db.Error
+	}
+	db := m.DB.Delete(apiKey)
+	return db.Error
+}
+
+// CreateSession stores a session created by either an api key or a user login
+func (m *ApiKey) CreateSession(apiKey string, user string, hash string, expiresAt int64) (*model.Session, error) {
+	session := &model.Session{Hash: hash, ApiKey: apiKey, User: user, ExpiresAt: expiresAt}
+	db := m.DB.Create(session)
+	return session, db.Error
+}
+
+func (m *ApiKey) FirstSessionByHash(hash string) (*model.Session, error) {
+	var session = &model.Session{}
+	db := m.DB.Where("hash = ?", hash).Find(&session)
+	return session, db.Error
+}
+
+func (m *ApiKey) DeleteSession(hash string) error {
+	db := m.DB.Where("hash = ?", hash).Delete(&model.Session{})
+	return db.Error
+}
+
+func (m *ApiKey) DeleteExpiredSessions() error {
+	db := m.DB.Where("expires_at < ?", time.Now().UnixMilli()).Delete(&model.Session{})
+	return db.Error
+}
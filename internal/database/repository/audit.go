@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"gorm.io/gorm"
+)
+
+type Audit struct {
+	DB *gorm.DB
+}
+
+func (t *Audit) Setup() {
+	t.DB.AutoMigrate(&model.Audit{})
+}
+
+// List returns the newest entries first, resource and resourceUuid are optional filters
+func (m *Audit) List(page int, perPage int, resource string, resourceUuid string) (*[]model.Audit, int64, error) {
+	query := m.DB.Model(&model.Audit{})
+	if resource != "" {
+		query = query.Where("resource = ?", resource)
+	}
+	if resourceUuid != "" {
+		query = query.Where("resource_uuid = ?", resourceUuid)
+	}
+
+	var total int64
+	query.Count(&total)
+	var audits = &[]model.Audit{}
+	db := query.Order("created_at DESC, id DESC").Limit(perPage).Offset(page * perPage).Find(&audits)
+	return audits, total, db.Error
+}
+
+func (m *Audit) Create(audit *model.Audit) (*model.Audit, error) {
+	audit.Uuid = uuid.NewString()
+	db := m.DB.Create(audit)
+	return audit, db.Error
+}
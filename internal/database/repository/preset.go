@@ -12,29 +12,55 @@ type Preset struct {
 }
 
 func (t *Preset) Setup() {
-	t.DB.AutoMigrate(&model.Preset{})
+	t.DB.AutoMigrate(&model.Preset{}, &model.PresetRevision{})
+
+	// presets created before revisions existed start with their current state as first revision
+	var presets []model.Preset
+	t.DB.Where("revision = 0").Find(&presets)
+	for i := range presets {
+		presets[i].Revision = 1
+		t.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&presets[i]).UpdateColumn("revision", 1).Error; err != nil {
+				return err
+			}
+			return tx.Create(presets[i].ToRevision()).Error
+		})
+	}
 }
 
 func (m *Preset) List(page int, perPage int) (*[]model.Preset, int64, error) {
 	total, _ := m.Count()
 	var presets = &[]model.Preset{}
-	m.DB.Order("created_at DESC").Limit(perPage).Offset(perPage * page).Find(&presets)
+	if page >= 0 && perPage >= 0 {
+		m.DB.Order("created_at DESC").Limit(perPage).Offset(perPage * page).Find(&presets)
+	} else {
+		m.DB.Order("created_at DESC").Find(&presets)
+	}
 	return presets, total, m.DB.Error
 }
 
+// Update stores the preset as a new revision
 func (m *Preset) Update(w *model.Preset) error {
-	m.DB.Save(w)
-	return m.DB.Error
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		w.Revision++
+		if err := tx.Save(w).Error; err != nil {
+			return err
+		}
+		return tx.Create(w.ToRevision()).Error
+	})
 }
 
+// Delete soft deletes the preset, updatedBy is stored first to record who deleted it
 func (m *Preset) Delete(w *model.Preset) error {
+	m.DB.Model(w).UpdateColumn("updated_by", w.UpdatedBy)
 	m.DB.Delete(w)
 	return m.DB.Error
 }
 
-func (m *Preset) Create(newPreset *dto.NewPreset) (*model.Preset, error) {
+func (m *Preset) Create(newPreset *dto.NewPreset, actor string, managed bool) (*model.Preset, error) {
 	preset := &model.Preset{
 		Uuid:           uuid.NewString(),
+		Extends:        newPreset.Extends,
 		Command:        newPreset.Command,
 		Name:           newPreset.Name,
 		Description:    newPreset.Description,
@@ -42,9 +68,36 @@ func (m *Preset) Create(newPreset *dto.NewPreset) (*model.Preset, error) {
 		OutputFile:     newPreset.OutputFile,
 		PreProcessing:  newPreset.PreProcessing,
 		PostProcessing: newPreset.PostProcessing,
+		Parameters:     newPreset.Parameters,
+		Library:        newPreset.Library,
+		LibraryVersion: newPreset.LibraryVersion,
+		Revision:       1,
+		Managed:        managed,
+		CreatedBy:      actor,
+		UpdatedBy:      actor,
 	}
-	db := m.DB.Create(preset)
-	return preset, db.Error
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(preset).Error; err != nil {
+			return err
+		}
+		return tx.Create(preset.ToRevision()).Error
+	})
+	return preset, err
+}
+
+// Revisions returns the revisions of a preset, newest first
+func (m *Preset) Revisions(uuid string, page int, perPage int) (*[]model.PresetRevision, int64, error) {
+	var total int64
+	m.DB.Model(&model.PresetRevision{}).Where("preset = ?", uuid).Count(&total)
+	var revisions = &[]model.PresetRevision{}
+	db := m.DB.Order("revision DESC").Where("preset = ?", uuid).Limit(perPage).Offset(perPage * page).Find(&revisions)
+	return revisions, total, db.Error
+}
+
+func (m *Preset) Revision(uuid string, revision uint) (*model.PresetRevision, error) {
+	var r *model.PresetRevision
+	db := m.DB.Where("preset = ? AND revision = ?", uuid, revision).First(&r)
+	return r, db.Error
 }
 
 func (m *Preset) First(uuid string) (*model.Preset, error) {
@@ -59,6 +112,13 @@ func (m *Preset) FindByUuid(uuid string) (*model.Preset, error) {
 	return preset, db.Error
 }
 
+// CountExtending returns the number of presets extending the given preset
+func (m *Preset) CountExtending(uuid string) (int64, error) {
+	var count int64
+	db := m.DB.Model(&model.Preset{}).Where("extends = ?", uuid).Count(&count)
+	return count, db.Error
+}
+
 func (m *Preset) Count() (int64, error) {
 	var count int64
 	db := m.DB.Model(&model.Preset{}).Count(&count)
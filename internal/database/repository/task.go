@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/welovemedia/ffmate/internal/database/model"
@@ -75,19 +76,31 @@ func (m *Task) List(page int, perPage int, status string) (*[]model.Task, int64,
 }
 
 func (m *Task) Create(newTask *dto.NewTask, batch string, source string, session string) (*model.Task, error) {
+	task := m.New(newTask, batch, source, session)
+	db := m.DB.Create(task)
+	return task, db.Error
+}
+
+// New returns the task as it would be created, without storing it
+func (m *Task) New(newTask *dto.NewTask, batch string, source string, session string) *model.Task {
 	task := &model.Task{
-		Uuid:       uuid.NewString(),
-		Command:    &dto.RawResolved{Raw: newTask.Command},
-		InputFile:  &dto.RawResolved{Raw: newTask.InputFile},
-		OutputFile: &dto.RawResolved{Raw: newTask.OutputFile},
-		Metadata:   newTask.Metadata, // Ensure Metadata is not nil
-		Name:       newTask.Name,
-		Priority:   newTask.Priority,
-		Progress:   0,
-		Source:     source,
-		Status:     dto.QUEUED,
-		Batch:      batch,
-		Session:    session,
+		Uuid:           uuid.NewString(),
+		Command:        &dto.RawResolved{Raw: newTask.Command},
+		InputFile:      &dto.RawResolved{Raw: newTask.InputFile},
+		OutputFile:     &dto.RawResolved{Raw: newTask.OutputFile},
+		Metadata:       newTask.Metadata, // Ensure Metadata is not nil
+		Params:         newTask.Params,
+		Name:           newTask.Name,
+		Preset:         newTask.Preset,
+		PresetRevision: newTask.PresetRevision,
+		Watchfolder:    newTask.Watchfolder,
+		Priority:       newTask.Priority,
+		Progress:       0,
+		Source:         source,
+		CreatedBy:      newTask.CreatedBy,
+		Status:         dto.QUEUED,
+		Batch:          batch,
+		Session:        session,
 	}
 	if newTask.PreProcessing != nil {
 		task.PreProcessing = &dto.PrePostProcessing{
@@ -100,12 +113,22 @@ func (m *Task) Create(newTask *dto.NewTask, batch string, source string, session
 			ScriptPath:  &dto.RawResolved{Raw: newTask.PostProcessing.ScriptPath},
 			SidecarPath: &dto.RawResolved{Raw: newTask.PostProcessing.SidecarPath},
 		}
+		if upload := newTask.PostProcessing.Upload; upload != nil {
+			task.PostProcessing.Upload = &dto.S3Upload{
+				S3Connection: upload.S3Connection,
+				Key:          &dto.RawResolved{Raw: upload.Key},
+				Files:        &dto.RawResolved{Raw: upload.Files},
+				PartSize:     upload.PartSize,
+				Retries:      upload.Retries,
+			}
+		}
 	}
-	db := m.DB.Create(task)
-	return task, db.Error
+	return task
 }
 
+// Delete soft deletes the task, updatedBy is stored first to record who deleted it
 func (m *Task) Delete(w *model.Task) error {
+	m.DB.Model(w).UpdateColumn("updated_by", w.UpdatedBy)
 	m.DB.Delete(w)
 	return m.DB.Error
 }
@@ -148,6 +171,13 @@ func (m *Task) CountByStatus(status dto.TaskStatus) (int64, error) {
 	return count, db.Error
 }
 
+// FinishedByWatchfolder returns the finished or deleted tasks of a watchfolder that changed since the given time (unix milli)
+func (m *Task) FinishedByWatchfolder(watchfolder string, since int64) (*[]model.Task, error) {
+	var tasks = &[]model.Task{}
+	db := m.DB.Unscoped().Where("watchfolder = ? AND ((updated_at >= ? AND status IN ?) OR deleted_at >= ?)", watchfolder, since, []dto.TaskStatus{dto.DONE_SUCCESSFUL, dto.DONE_ERROR, dto.DONE_CANCELED}, time.UnixMilli(since)).Find(tasks)
+	return tasks, db.Error
+}
+
 func (m *Task) NextQueued() (*model.Task, error) {
 	var task *model.Task
 	db := m.DB.Order("priority DESC, created_at ASC").Where("status", dto.QUEUED).First(&task)
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	DB *gorm.DB
+}
+
+func (t *User) Setup() {
+	t.DB.AutoMigrate(&model.User{})
+}
+
+func (m *User) List() (*[]model.User, error) {
+	var users = &[]model.User{}
+	db := m.DB.Order("username ASC").Find(&users)
+	return users, db.Error
+}
+
+func (m *User) First(uuid string) (*model.User, error) {
+	var user = &model.User{}
+	db := m.DB.Where("uuid = ?", uuid).Find(&user)
+	return user, db.Error
+}
+
+func (m *User) FirstByUsername(username string) (*model.User, error) {
+	var user = &model.User{}
+	db := m.DB.Where("username = ?", username).Find(&user)
+	return user, db.Error
+}
+
+func (m *User) Create(newUser *dto.NewUser, password string) (*model.User, error) {
+	user := &model.User{Uuid: uuid.NewString(), Username: newUser.Username, Password: password, Role: newUser.Role}
+	db := m.DB.Create(user)
+	return user, db.Error
+}
+
+func (m *User) Update(user *model.User) error {
+	db := m.DB.Save(user)
+	return db.Error
+}
+
+func (m *User) Touch(user *model.User) error {
+	db := m.DB.Model(user).UpdateColumn("last_login_at", time.Now().UnixMilli())
+	return db.Error
+}
+
+// Delete removes the user together with its sessions and api keys
+func (m *User) Delete(user *model.User) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		var apiKeys []string
+		if err := tx.Model(&model.ApiKey{}).Where("user = ?", user.Uuid).Pluck("uuid", &apiKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user = ? OR api_key IN ?", user.Uuid, apiKeys).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user = ?", user.Uuid).Delete(&model.ApiKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
+
+func (m *User) Count() (int64, error) {
+	var count int64
+	db := m.DB.Model(&model.User{}).Count(&count)
+	return count, db.Error
+}
@@ -57,17 +57,24 @@ func (m *Watchfolder) Update(w *model.Watchfolder) (*model.Watchfolder, error) {
 	return w, m.DB.Error
 }
 
-func (m *Watchfolder) Create(newWatchfolder *dto.NewWatchfolder) (*model.Watchfolder, error) {
+func (m *Watchfolder) Create(newWatchfolder *dto.NewWatchfolder, managed bool) (*model.Watchfolder, error) {
 	watchfolder := &model.Watchfolder{
 		Uuid:         uuid.NewString(),
 		Name:         newWatchfolder.Name,
 		Description:  newWatchfolder.Description,
 		Preset:       newWatchfolder.Preset,
+		Params:       newWatchfolder.Params,
+		Type:         newWatchfolder.Type,
+		S3:           newWatchfolder.S3,
 		Path:         newWatchfolder.Path,
 		Interval:     newWatchfolder.Interval,
 		Filter:       newWatchfolder.Filter,
+		Trigger:      newWatchfolder.Trigger,
+		Schedule:     newWatchfolder.Schedule,
 		GrowthChecks: newWatchfolder.GrowthChecks,
 		Suspended:    newWatchfolder.Suspended,
+		Managed:      managed,
+		Health:       dto.WATCHFOLDER_OK,
 	}
 	db := m.DB.Create(watchfolder)
 	return watchfolder, db.Error
@@ -18,7 +18,11 @@ func (t *Webhook) Setup() {
 func (m *Webhook) List(page int, perPage int) (*[]model.Webhook, int64, error) {
 	total, _ := m.Count()
 	var webhooks = &[]model.Webhook{}
-	m.DB.Order("event ASC, created_at DESC").Limit(perPage).Offset(page * perPage).Find(&webhooks)
+	if page >= 0 && perPage >= 0 {
+		m.DB.Order("event ASC, created_at DESC").Limit(perPage).Offset(page * perPage).Find(&webhooks)
+	} else {
+		m.DB.Order("event ASC, created_at DESC").Find(&webhooks)
+	}
 	return webhooks, total, m.DB.Error
 }
 
@@ -45,14 +49,19 @@ func (m *Webhook) Delete(w *model.Webhook) error {
 	return m.DB.Error
 }
 
+func (m *Webhook) Update(w *model.Webhook) (*model.Webhook, error) {
+	m.DB.Save(w)
+	return w, m.DB.Error
+}
+
 func (m *Webhook) ListByEvent(event dto.WebhookEvent) (*[]model.Webhook, error) {
 	var webhooks = &[]model.Webhook{}
 	m.DB.Order("created_at DESC").Where("event = ?", event).Find(&webhooks)
 	return webhooks, m.DB.Error
 }
 
-func (m *Webhook) Create(event dto.WebhookEvent, url string) (*model.Webhook, error) {
-	webhook := &model.Webhook{Uuid: uuid.NewString(), Event: event, Url: url}
+func (m *Webhook) Create(newWebhook *dto.NewWebhook, managed bool) (*model.Webhook, error) {
+	webhook := &model.Webhook{Uuid: uuid.NewString(), Event: newWebhook.Event, Url: newWebhook.Url, Secret: newWebhook.Secret, Filter: newWebhook.Filter, Headers: newWebhook.Headers, Template: newWebhook.Template, ContentType: newWebhook.ContentType, Managed: managed}
 	db := m.DB.Create(webhook)
 	return webhook, db.Error
 }
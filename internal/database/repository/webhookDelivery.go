@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type WebhookDelivery struct {
+	DB *gorm.DB
+}
+
+func (t *WebhookDelivery) Setup() {
+	t.DB.AutoMigrate(&model.WebhookDelivery{})
+}
+
+func (m *WebhookDelivery) ListByWebhook(webhook string, page int, perPage int) (*[]model.WebhookDelivery, int64, error) {
+	var total int64
+	m.DB.Model(&model.WebhookDelivery{}).Where("webhook = ?", webhook).Count(&total)
+	var deliveries = &[]model.WebhookDelivery{}
+	db := m.DB.Where("webhook = ?", webhook).Order("created_at DESC, id DESC").Limit(perPage).Offset(page * perPage).Find(&deliveries)
+	return deliveries, total, db.Error
+}
+
+func (m *WebhookDelivery) First(webhook string, uuid string) (*model.WebhookDelivery, error) {
+	var delivery = &model.WebhookDelivery{}
+	db := m.DB.Where("webhook = ? AND uuid = ?", webhook, uuid).Find(&delivery)
+	return delivery, db.Error
+}
+
+func (m *WebhookDelivery) Create(webhook *model.Webhook, payload []byte, status dto.WebhookDeliveryStatus, reason string) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{Uuid: uuid.NewString(), Webhook: webhook.Uuid, Event: webhook.Event, Url: webhook.Url, Status: status, Error: reason, Payload: string(payload)}
+	db := m.DB.Create(delivery)
+	return delivery, db.Error
+}
+
+func (m *WebhookDelivery) Update(delivery *model.WebhookDelivery) error {
+	db := m.DB.Save(delivery)
+	return db.Error
+}
+
+// NextPending returns the oldest pending delivery, skipping the given urls
+func (m *WebhookDelivery) NextPending(excludeUrls []string) (*model.WebhookDelivery, error) {
+	var deliveries = []model.WebhookDelivery{}
+	db := m.DB.Where("status = ?", dto.DELIVERY_PENDING)
+	if len(excludeUrls) > 0 {
+		db = db.Where("url NOT IN ?", excludeUrls)
+	}
+	db = db.Order("id ASC").Limit(1).Find(&deliveries)
+	if db.Error != nil || len(deliveries) == 0 {
+		return nil, db.Error
+	}
+	return &deliveries[0], nil
+}
+
+func (m *WebhookDelivery) CountPending(webhook string) (int64, error) {
+	var count int64
+	db := m.DB.Model(&model.WebhookDelivery{}).Where("webhook = ? AND status = ?", webhook, dto.DELIVERY_PENDING).Count(&count)
+	return count, db.Error
+}
+
+// DeleteFinishedBefore deletes successful and failed deliveries last updated before the given time
+func (m *WebhookDelivery) DeleteFinishedBefore(before time.Time) (int64, error) {
+	db := m.DB.Where("status != ? AND updated_at < ?", dto.DELIVERY_PENDING, before).Delete(&model.WebhookDelivery{})
+	return db.RowsAffected, db.Error
+}
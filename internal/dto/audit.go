@@ -0,0 +1,71 @@
+package dto
+
+import "time"
+
+type AuditAction string
+
+const (
+	AUDIT_CREATE AuditAction = "CREATE"
+	AUDIT_UPDATE AuditAction = "UPDATE"
+	AUDIT_DELETE AuditAction = "DELETE"
+)
+
+type AuditResource string
+
+const (
+	AUDIT_PRESET      AuditResource = "preset"
+	AUDIT_WEBHOOK     AuditResource = "webhook"
+	AUDIT_WATCHFOLDER AuditResource = "watchfolder"
+)
+
+// Actor is the caller of a change, recorded in createdBy/updatedBy fields and the audit log
+type Actor struct {
+	Name string // eg. "user:jane", empty if authentication is disabled
+	Ip   string
+}
+
+// GetName is safe to call for changes without actor, eg. by the system itself
+func (a *Actor) GetName() string {
+	if a == nil {
+		return ""
+	}
+	return a.Name
+}
+
+// CONFIG_ACTOR applies the config directory, the resources it manages are read-only for everyone else
+var CONFIG_ACTOR = &Actor{Name: "config"}
+
+// IsConfig reports whether the change is applied from the config directory
+func (a *Actor) IsConfig() bool {
+	return a != nil && a == CONFIG_ACTOR
+}
+
+func (a *Actor) GetIp() string {
+	if a == nil {
+		return ""
+	}
+	return a.Ip
+}
+
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type Audit struct {
+	Uuid string `json:"uuid"`
+
+	Resource     AuditResource `json:"resource"`
+	ResourceUuid string        `json:"resourceUuid"`
+	Action       AuditAction   `json:"action"`
+
+	Actor    string `json:"actor,omitempty"`
+	SourceIp string `json:"sourceIp,omitempty"`
+
+	Before  InterfaceMap  `json:"before,omitempty"`
+	After   InterfaceMap  `json:"after,omitempty"`
+	Changes []AuditChange `json:"changes"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,161 @@
+package dto
+
+import "time"
+
+type Scope string
+
+const (
+	SCOPE_READ   Scope = "read"   // read everything
+	SCOPE_SUBMIT Scope = "submit" // submit, cancel, restart and delete tasks
+	SCOPE_ADMIN  Scope = "admin"  // manage presets, webhooks, watchfolders and debugging
+)
+
+type Role string
+
+const (
+	ROLE_VIEWER   Role = "viewer"
+	ROLE_OPERATOR Role = "operator"
+	ROLE_ADMIN    Role = "admin"
+)
+
+type Permission string
+
+const (
+	PERMISSION_READ Permission = "read" // list and get all resources, websocket and metrics
+
+	PERMISSION_TASK_SUBMIT Permission = "task.submit"
+	PERMISSION_TASK_CANCEL Permission = "task.cancel" // cancel and restart
+	PERMISSION_TASK_DELETE Permission = "task.delete"
+
+	PERMISSION_PRESET_WRITE      Permission = "preset.write"
+	PERMISSION_WEBHOOK_WRITE     Permission = "webhook.write"
+	PERMISSION_WATCHFOLDER_WRITE Permission = "watchfolder.write"
+	PERMISSION_DEBUG_WRITE       Permission = "debug.write"
+
+	PERMISSION_USER_MANAGE Permission = "user.manage" // users and api keys
+	PERMISSION_AUDIT_READ  Permission = "audit.read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	ROLE_VIEWER:   {PERMISSION_READ},
+	ROLE_OPERATOR: {PERMISSION_READ, PERMISSION_TASK_SUBMIT, PERMISSION_TASK_CANCEL, PERMISSION_TASK_DELETE},
+	ROLE_ADMIN: {PERMISSION_READ, PERMISSION_TASK_SUBMIT, PERMISSION_TASK_CANCEL, PERMISSION_TASK_DELETE,
+		PERMISSION_PRESET_WRITE, PERMISSION_WEBHOOK_WRITE, PERMISSION_WATCHFOLDER_WRITE, PERMISSION_DEBUG_WRITE, PERMISSION_USER_MANAGE, PERMISSION_AUDIT_READ},
+}
+
+// api key scopes grant the permissions of a role
+var scopeRoles = map[Scope]Role{
+	SCOPE_READ:   ROLE_VIEWER,
+	SCOPE_SUBMIT: ROLE_OPERATOR,
+	SCOPE_ADMIN:  ROLE_ADMIN,
+}
+
+func IsValidScope(scope Scope) bool {
+	_, ok := scopeRoles[scope]
+	return ok
+}
+
+func IsValidRole(role Role) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Auth describes the authenticated caller of a request
+type Auth struct {
+	User   string  `json:"user,omitempty"`   // uuid of the user logged in or owning the api key
+	ApiKey string  `json:"apiKey,omitempty"` // uuid of the api key used directly or to create the session
+	Name   string  `json:"name"`
+	Role   Role    `json:"role,omitempty"`
+	Scopes []Scope `json:"scopes,omitempty"`
+}
+
+// Can reports whether the caller has a permission. Callers authenticated by an api key owned by
+// a user need both, a matching scope and a role granting the permission.
+func (a *Auth) Can(permission Permission) bool {
+	if a.User != "" && !a.Role.Can(permission) {
+		return false
+	}
+	if a.ApiKey == "" {
+		return a.User != ""
+	}
+	for _, scope := range a.Scopes {
+		if scopeRoles[scope].Can(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor identifies the caller in audit fields like createdBy, eg. "user:jane" or "apikey:ci"
+func (a *Auth) Actor() string {
+	if a.User != "" && a.ApiKey == "" {
+		return "user:" + a.Name
+	}
+	return "apikey:" + a.Name
+}
+
+type NewApiKey struct {
+	User   string  `json:"-"` // set if created by a logged in user
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+type ApiKey struct {
+	Uuid   string  `json:"uuid"`
+	User   string  `json:"user,omitempty"`
+	Name   string  `json:"name"`
+	Prefix string  `json:"prefix"` // first characters of the key to recognize it
+	Scopes []Scope `json:"scopes"`
+
+	LastUsedAt int64 `json:"lastUsedAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CreatedApiKey is only returned once on creation as the key is stored hashed
+type CreatedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// Login either by api key or by username and password
+type Login struct {
+	ApiKey   string `json:"apiKey,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type NewUser struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"` // unchanged on update if empty
+	Role     Role   `json:"role"`
+}
+
+type User struct {
+	Uuid     string `json:"uuid"`
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+
+	LastLoginAt int64 `json:"lastLoginAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Session struct {
+	Token     string  `json:"token"`
+	Name      string  `json:"name"`
+	Role      Role    `json:"role,omitempty"`
+	Scopes    []Scope `json:"scopes,omitempty"`
+	ExpiresAt int64   `json:"expiresAt"`
+}
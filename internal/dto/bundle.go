@@ -0,0 +1,66 @@
+package dto
+
+import "time"
+
+// BUNDLE_VERSION is increased whenever the bundle format changes incompatibly
+const BUNDLE_VERSION = 1
+
+// Bundle holds presets, watchfolders and webhooks to move them between instances, secrets are never exported
+type Bundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+
+	Presets      []BundlePreset      `json:"presets,omitempty"`
+	Watchfolders []BundleWatchfolder `json:"watchfolders,omitempty"`
+	Webhooks     []BundleWebhook     `json:"webhooks,omitempty"`
+}
+
+// BundlePreset is a preset with the uuid it had on the exporting instance, references to it are remapped on import
+type BundlePreset struct {
+	Uuid string `json:"uuid"`
+	NewPreset
+}
+
+type BundleWatchfolder struct {
+	Uuid string `json:"uuid"`
+	NewWatchfolder
+}
+
+type BundleWebhook struct {
+	Uuid string `json:"uuid"`
+	NewWebhook
+}
+
+type BundleConflict string
+
+const (
+	CONFLICT_SKIP      BundleConflict = "skip"      // keep the existing resource
+	CONFLICT_OVERWRITE BundleConflict = "overwrite" // replace the existing resource
+	CONFLICT_RENAME    BundleConflict = "rename"    // import as a new resource with a different name
+)
+
+type BundleAction string
+
+const (
+	BUNDLE_CREATE    BundleAction = "create"
+	BUNDLE_OVERWRITE BundleAction = "overwrite"
+	BUNDLE_RENAME    BundleAction = "rename"
+	BUNDLE_SKIP      BundleAction = "skip"
+	BUNDLE_DELETE    BundleAction = "delete" // managed resources no longer declared in the config directory
+	BUNDLE_FAILED    BundleAction = "failed"
+)
+
+type BundleImport struct {
+	DryRun bool               `json:"dryRun"`
+	Items  []BundleImportItem `json:"items"`
+}
+
+// BundleImportItem is the outcome of importing a single resource of a bundle
+type BundleImportItem struct {
+	Resource AuditResource `json:"resource"`
+	Name     string        `json:"name"`
+	Uuid     string        `json:"uuid,omitempty"`   // uuid within the bundle
+	Target   string        `json:"target,omitempty"` // uuid on this instance, empty for new resources of a dry run
+	Action   BundleAction  `json:"action"`
+	Error    string        `json:"error,omitempty"`
+}
@@ -1,7 +1,9 @@
 package dto
 
 type NewPreset struct {
-	Command string `json:"command"`
+	Extends string `json:"extends,omitempty"` // uuid of the base preset, fields left empty are inherited from it
+
+	Command string `json:"command"` // ${BASE_COMMAND} inserts the command of the base preset
 
 	Priority uint `json:"priority"`
 
@@ -10,8 +12,14 @@ type NewPreset struct {
 	PreProcessing  *NewPrePostProcessing `json:"preProcessing"`
 	PostProcessing *NewPrePostProcessing `json:"postProcessing"`
 
+	Parameters PresetParameters `json:"parameters,omitempty"`
+
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
 	GlobalPresetName string `json:"globalPresetName"`
+
+	// set when installing from the preset library, never via the api
+	Library        string `json:"-"`
+	LibraryVersion uint   `json:"-"`
 }
@@ -1,8 +1,12 @@
 package dto
 
 type NewTask struct {
-	Command string `json:"command"`
-	Preset  string `json:"preset"`
+	Command        string `json:"command"`
+	Preset         string `json:"preset"`
+	PresetRevision uint   `json:"presetRevision,omitempty"` // a specific revision of the preset, defaults to the current one
+
+	Watchfolder string `json:"-"` // set if the task was created by a watchfolder
+	CreatedBy   string `json:"-"` // set if the task was submitted by an authenticated caller
 
 	Name string `json:"name"`
 
@@ -10,6 +14,7 @@ type NewTask struct {
 	OutputFile string `json:"outputFile"`
 
 	Metadata *InterfaceMap `json:"metadata,omitempty"` // Additional metadata for the task
+	Params   *InterfaceMap `json:"params,omitempty"`   // values for the parameters of the preset
 
 	Priority uint `json:"priority"`
 
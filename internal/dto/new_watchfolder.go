@@ -4,13 +4,18 @@ type NewWatchfolder struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Path         string `json:"path"`
-	Interval     int    `json:"interval"`
-	GrowthChecks int    `json:"growthChecks"`
+	Type         WatchfolderType `json:"type"`
+	S3           *WatchfolderS3  `json:"s3"`
+	Path         string          `json:"path"`
+	Interval     int             `json:"interval"`
+	GrowthChecks int             `json:"growthChecks"`
 
-	Filter *WatchfolderFilter `json:"filter"`
+	Filter   *WatchfolderFilter   `json:"filter"`
+	Trigger  *WatchfolderTrigger  `json:"trigger"`
+	Schedule *WatchfolderSchedule `json:"schedule"`
 
 	Suspended bool `json:"suspended"`
 
-	Preset string `json:"preset"`
+	Preset string        `json:"preset"`
+	Params *InterfaceMap `json:"params,omitempty"` // values for the parameters of the preset
 }
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
 type WebhookEvent string
 
 const (
@@ -10,6 +16,14 @@ const (
 	TASK_UPDATED WebhookEvent = "task.updated"
 	TASK_DELETED WebhookEvent = "task.deleted"
 
+	TASK_STARTED           WebhookEvent = "task.started"
+	TASK_SUCCEEDED         WebhookEvent = "task.succeeded"
+	TASK_FAILED            WebhookEvent = "task.failed"
+	TASK_CANCELED          WebhookEvent = "task.canceled"
+	TASK_RETRYING          WebhookEvent = "task.retrying"
+	PRE_PROCESSING_FAILED  WebhookEvent = "preProcessing.failed"
+	POST_PROCESSING_FAILED WebhookEvent = "postProcessing.failed"
+
 	PRESET_CREATED WebhookEvent = "preset.created"
 	PRESET_UPDATED WebhookEvent = "preset.updated"
 	PRESET_DELETED WebhookEvent = "preset.deleted"
@@ -20,9 +34,46 @@ const (
 	WATCHFOLDER_CREATED WebhookEvent = "watchfolder.created"
 	WATCHFOLDER_UPDATED WebhookEvent = "watchfolder.updated"
 	WATCHFOLDER_DELETED WebhookEvent = "watchfolder.deleted"
+	WATCHFOLDER_ERROR   WebhookEvent = "watchfolder.error"
 )
 
 type NewWebhook struct {
 	Event WebhookEvent `json:"event"`
 	Url   string       `json:"url"`
+
+	// Secret is used to sign deliveries with HMAC-SHA256, it is never returned by the api
+	Secret string `json:"secret,omitempty"`
+
+	Filter  *WebhookFilter    `json:"filter,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // additional request headers, e.g. for authentication
+
+	// Template replaces the default {event, data} payload, it is a go template executed against that payload
+	Template    string `json:"template,omitempty"`
+	ContentType string `json:"contentType,omitempty"` // defaults to application/json
+}
+
+// WebhookFilter restricts which task events are delivered. All given conditions must match,
+// events without a task payload are only subject to SkipProgress.
+type WebhookFilter struct {
+	Statuses     []TaskStatus      `json:"statuses,omitempty"`
+	Presets      []string          `json:"presets,omitempty"`
+	Watchfolders []string          `json:"watchfolders,omitempty"`
+	Batches      []string          `json:"batches,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`     // dot separated path into the metadata and the expected value
+	SkipProgress bool              `json:"skipProgress,omitempty"` // skip updates that only changed the progress of a task
+}
+
+func (n WebhookFilter) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WebhookFilter) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
 }
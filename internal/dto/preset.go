@@ -3,7 +3,9 @@ package dto
 import "time"
 
 type Preset struct {
-	Uuid string `json:"uuid"`
+	Uuid     string `json:"uuid"`
+	Revision uint   `json:"revision"`
+	Extends  string `json:"extends,omitempty"`
 
 	Command     string `json:"command"`
 	Name        string `json:"name"`
@@ -16,6 +18,16 @@ type Preset struct {
 	PreProcessing  *NewPrePostProcessing `json:"preProcessing,omitempty"`
 	PostProcessing *NewPrePostProcessing `json:"postProcessing,omitempty"`
 
+	Parameters PresetParameters `json:"parameters,omitempty"`
+
+	Library        string `json:"library,omitempty"`        // id of the library preset it was installed from
+	LibraryVersion uint   `json:"libraryVersion,omitempty"` // version of the library preset it was installed from
+
+	Managed bool `json:"managed"` // declared in the config directory and read-only via the api
+
+	CreatedBy string `json:"createdBy,omitempty"`
+	UpdatedBy string `json:"updatedBy,omitempty"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
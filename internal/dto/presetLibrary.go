@@ -0,0 +1,13 @@
+package dto
+
+// LibraryPreset is a preset shipped with ffmate, its version is increased whenever the preset changes
+type LibraryPreset struct {
+	Id       string `json:"id"`
+	Version  uint   `json:"version"`
+	Category string `json:"category"`
+	NewPreset
+
+	Installed        string `json:"installed,omitempty"`        // uuid of the preset installed from the library
+	InstalledVersion uint   `json:"installedVersion,omitempty"` // version of the library preset it was installed from
+	UpdateAvailable  bool   `json:"updateAvailable"`
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type PresetParameterType string
+
+const (
+	PARAMETER_NUMBER  PresetParameterType = "number"
+	PARAMETER_ENUM    PresetParameterType = "enum"
+	PARAMETER_STRING  PresetParameterType = "string"
+	PARAMETER_BOOLEAN PresetParameterType = "boolean"
+)
+
+// PresetParameter declares a typed input of a preset, referenced in its templates as ${param.<name>}.
+// Parameters without a default must be supplied by every task using the preset.
+type PresetParameter struct {
+	Type        PresetParameterType `json:"type"`
+	Description string              `json:"description,omitempty"`
+	Default     any                 `json:"default,omitempty"`
+
+	Min *float64 `json:"min,omitempty"` // lower bound of a number
+	Max *float64 `json:"max,omitempty"` // upper bound of a number
+
+	Values []string `json:"values,omitempty"` // allowed values of an enum
+}
+
+type PresetParameters map[string]PresetParameter
+
+func (p PresetParameters) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *PresetParameters) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, p)
+}
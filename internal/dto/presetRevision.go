@@ -0,0 +1,34 @@
+package dto
+
+import "time"
+
+// PresetRevision is the state of a preset after one of its changes, revisions are never modified
+type PresetRevision struct {
+	Preset   string `json:"preset"`
+	Revision uint   `json:"revision"`
+	Extends  string `json:"extends,omitempty"`
+
+	Command     string `json:"command"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	OutputFile string `json:"outputFile"`
+
+	Priority uint `json:"priority"`
+
+	PreProcessing  *NewPrePostProcessing `json:"preProcessing,omitempty"`
+	PostProcessing *NewPrePostProcessing `json:"postProcessing,omitempty"`
+
+	Parameters PresetParameters `json:"parameters,omitempty"`
+
+	CreatedBy string    `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PresetRevisionDiff lists the fields that changed between two revisions of a preset
+type PresetRevisionDiff struct {
+	Preset  string        `json:"preset"`
+	From    uint          `json:"from"`
+	To      uint          `json:"to"`
+	Changes []AuditChange `json:"changes"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+// S3Connection describes how to reach a bucket on an S3-compatible storage.
+// If no access key is given, credentials are read from the AWS_* or MINIO_* environment variables.
+type S3Connection struct {
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey,omitempty"`
+	UseSSL    bool   `json:"useSSL"`
+}
+
+// NewS3Upload uploads the output of a task as built-in postProcessing action.
+// Key and Files support wildcards, a Key ending with '/' is used as prefix for the file names.
+// Files is an optional glob and defaults to the resolved output file.
+type NewS3Upload struct {
+	S3Connection
+	Key      string `json:"key"`
+	Files    string `json:"files,omitempty"`
+	PartSize uint64 `json:"partSize,omitempty"` // in bytes, multipart uploads are used for files larger than a part
+	Retries  int    `json:"retries,omitempty"`
+}
+
+type S3Upload struct {
+	S3Connection
+	Key      *RawResolved `json:"key"`
+	Files    *RawResolved `json:"files,omitempty"`
+	PartSize uint64       `json:"partSize,omitempty"`
+	Retries  int          `json:"retries,omitempty"`
+	Urls     []string     `json:"urls,omitempty"`
+}
@@ -20,18 +20,44 @@ const (
 )
 
 type NewPrePostProcessing struct {
-	ScriptPath  string `json:"scriptPath,omitempty"`
-	SidecarPath string `json:"sidecarPath,omitempty"`
+	ScriptPath  string       `json:"scriptPath,omitempty"`
+	SidecarPath string       `json:"sidecarPath,omitempty"`
+	Upload      *NewS3Upload `json:"upload,omitempty"`
 }
 
 type PrePostProcessing struct {
 	ScriptPath  *RawResolved `json:"scriptPath,omitempty"`
 	SidecarPath *RawResolved `json:"sidecarPath,omitempty"`
+	Upload      *S3Upload    `json:"upload,omitempty"`
 	Error       string       `json:"error,omitempty"`
 	StartedAt   int64        `json:"startedAt,omitempty"`
 	FinishedAt  int64        `json:"finishedAt,omitempty"`
 }
 
+// WithoutSecrets returns a copy that is safe to expose via the api
+func (n *NewPrePostProcessing) WithoutSecrets() *NewPrePostProcessing {
+	if n == nil || n.Upload == nil {
+		return n
+	}
+	c := *n
+	upload := *n.Upload
+	upload.SecretKey = ""
+	c.Upload = &upload
+	return &c
+}
+
+// WithoutSecrets returns a copy that is safe to expose via the api and sidecar files
+func (p *PrePostProcessing) WithoutSecrets() *PrePostProcessing {
+	if p == nil || p.Upload == nil {
+		return p
+	}
+	c := *p
+	upload := *p.Upload
+	upload.SecretKey = ""
+	c.Upload = &upload
+	return &c
+}
+
 type RawResolved struct {
 	Raw      string `json:"raw"`
 	Resolved string `json:"resolved,omitempty"`
@@ -43,11 +69,16 @@ type Task struct {
 
 	Name string `json:"name,omitempty"`
 
+	Preset         string `json:"preset,omitempty"`
+	PresetRevision uint   `json:"presetRevision,omitempty"`
+	Watchfolder    string `json:"watchfolder,omitempty"`
+
 	Command    *RawResolved `json:"command"`
 	InputFile  *RawResolved `json:"inputFile"`
 	OutputFile *RawResolved `json:"outputFile"`
 
 	Metadata *InterfaceMap `json:"metadata,omitempty"` // Additional metadata for the task
+	Params   *InterfaceMap `json:"params,omitempty"`   // parameters of the preset including their defaults
 
 	Status    TaskStatus `json:"status"`
 	Progress  float64    `json:"progress"`
@@ -59,6 +90,9 @@ type Task struct {
 
 	Source string `json:"source,omitempty"`
 
+	CreatedBy string `json:"createdBy,omitempty"`
+	UpdatedBy string `json:"updatedBy,omitempty"` // caller who last canceled, restarted or deleted the task
+
 	PreProcessing  *PrePostProcessing `json:"preProcessing,omitempty"`
 	PostProcessing *PrePostProcessing `json:"postProcessing,omitempty"`
 
@@ -136,3 +170,9 @@ func (n *NewPrePostProcessing) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(bytes, n)
 }
+
+// DryRunTask is a task resolved like right before processing, it is never created
+type DryRunTask struct {
+	Task
+	Arguments []string `json:"arguments"` // the arguments ffmpeg would be started with
+}
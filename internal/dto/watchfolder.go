@@ -6,27 +6,64 @@ import (
 	"errors"
 )
 
+type WatchfolderHealth string
+
+const (
+	WATCHFOLDER_OK       WatchfolderHealth = "OK"
+	WATCHFOLDER_DEGRADED WatchfolderHealth = "DEGRADED"
+	WATCHFOLDER_FAILING  WatchfolderHealth = "FAILING"
+)
+
+type WatchfolderType string
+
+const (
+	WATCHFOLDER_LOCAL WatchfolderType = "local"
+	WATCHFOLDER_S3    WatchfolderType = "s3"
+)
+
+type WatchfolderS3Mode string
+
+const (
+	S3_DOWNLOAD WatchfolderS3Mode = "download"
+	S3_PRESIGN  WatchfolderS3Mode = "presign"
+)
+
 type Watchfolder struct {
 	Uuid string `json:"uuid"`
 
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Path         string `json:"path"`
-	Interval     int    `json:"interval"`
-	GrowthChecks int    `json:"growthChecks"`
+	Type         WatchfolderType `json:"type"`
+	S3           *WatchfolderS3  `json:"s3,omitempty"`
+	Path         string          `json:"path"`
+	Interval     int             `json:"interval"`
+	GrowthChecks int             `json:"growthChecks"`
 
 	Suspended bool `json:"suspended"`
+	Managed   bool `json:"managed"` // declared in the config directory and read-only via the api
 
-	Filter *WatchfolderFilter `json:"filter"`
+	Filter   *WatchfolderFilter   `json:"filter"`
+	Trigger  *WatchfolderTrigger  `json:"trigger,omitempty"`
+	Schedule *WatchfolderSchedule `json:"schedule,omitempty"`
 
-	Preset string `json:"preset"`
+	Preset string        `json:"preset"`
+	Params *InterfaceMap `json:"params,omitempty"`
 
 	CreatedAt int64 `json:"createdAt"`
 	UpdatedAt int64 `json:"updatedAt"`
 
 	Error     string `json:"error,omitempty"`
 	LastCheck int64  `json:"lastCheck"`
+
+	// NextActiveAt is only set while the watchfolder is outside of its schedule
+	NextActiveAt int64 `json:"nextActiveAt,omitempty"`
+
+	Health              WatchfolderHealth `json:"health"`
+	ConsecutiveFailures int               `json:"consecutiveFailures"`
+	LastSuccessfulScan  int64             `json:"lastSuccessfulScan"`
+	FilesSeen           int64             `json:"filesSeen"`
+	FilesIngested       int64             `json:"filesIngested"`
 }
 
 type WatchfolderFilter struct {
@@ -38,6 +75,38 @@ type WatchfolderFilterExtensions struct {
 	Include []string `json:"include"`
 }
 
+// WatchfolderTrigger switches a watchfolder into grouped ingestion. Files sharing
+// the same basename are collected into a group and a single task is created once
+// a file matching one of the trigger extensions has arrived.
+type WatchfolderTrigger struct {
+	Extensions        []string `json:"extensions"`
+	SidecarExtensions []string `json:"sidecarExtensions"`
+}
+
+// WatchfolderSchedule limits scanning to the minutes matched by a cron expression
+// or to weekly time windows. Both are evaluated in the given timezone (default: local).
+type WatchfolderSchedule struct {
+	Cron     string                      `json:"cron,omitempty"`
+	Windows  []WatchfolderScheduleWindow `json:"windows,omitempty"`
+	Timezone string                      `json:"timezone,omitempty"`
+}
+
+type WatchfolderScheduleWindow struct {
+	Days  []string `json:"days"`  // mon, tue, wed, thu, fri, sat, sun (empty: every day)
+	Start string   `json:"start"` // HH:MM
+	End   string   `json:"end"`   // HH:MM (spans midnight if before start)
+}
+
+// WatchfolderS3 configures a watchfolder that lists a bucket prefix instead of a local path.
+// New objects are either downloaded into ScratchDir or handed to ffmpeg as presigned url.
+// Downloaded objects are removed once their task finished, failed, was canceled or deleted.
+type WatchfolderS3 struct {
+	S3Connection
+	Prefix     string            `json:"prefix"`
+	Mode       WatchfolderS3Mode `json:"mode"`
+	ScratchDir string            `json:"scratchDir,omitempty"`
+}
+
 func (n WatchfolderFilter) Value() (driver.Value, error) {
 	return json.Marshal(n)
 }
@@ -52,3 +121,48 @@ func (n *WatchfolderFilter) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(bytes, n)
 }
+
+func (n WatchfolderTrigger) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderTrigger) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+func (n WatchfolderSchedule) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderSchedule) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+func (n WatchfolderS3) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderS3) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
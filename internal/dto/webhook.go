@@ -3,11 +3,48 @@ package dto
 import "time"
 
 type Webhook struct {
-	Event WebhookEvent `json:"event"`
-	Url   string       `json:"url"`
+	Event  WebhookEvent `json:"event"`
+	Url    string       `json:"url"`
+	Signed bool         `json:"signed"`
+
+	Filter  *WebhookFilter    `json:"filter,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // values are masked
+
+	Template    string `json:"template,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+
+	Managed bool `json:"managed"` // declared in the config directory and read-only via the api
 
 	Uuid string `json:"uuid"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+type WebhookDeliveryStatus string
+
+const (
+	DELIVERY_PENDING    WebhookDeliveryStatus = "PENDING"
+	DELIVERY_SUCCESSFUL WebhookDeliveryStatus = "SUCCESSFUL"
+	DELIVERY_FAILED     WebhookDeliveryStatus = "FAILED"
+)
+
+type WebhookDelivery struct {
+	Uuid    string       `json:"uuid"`
+	Webhook string       `json:"webhook"`
+	Event   WebhookEvent `json:"event"`
+	Url     string       `json:"url"`
+
+	Status     WebhookDeliveryStatus `json:"status"`
+	Attempts   int                   `json:"attempts"`
+	StatusCode int                   `json:"statusCode,omitempty"`
+	Latency    int64                 `json:"latency"` // in milliseconds
+	Response   string                `json:"response,omitempty"`
+	Error      string                `json:"error,omitempty"`
+
+	// Payload is the body as it was sent, templates may render formats other than json
+	Payload string `json:"payload"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
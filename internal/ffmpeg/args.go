@@ -0,0 +1,149 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// Option is a single ffmpeg option, the name is stored without its leading dash (e.g. "c:v")
+type Option struct {
+	Name  string
+	Value string
+}
+
+// File is an input or output of an ffmpeg command including the options preceding it
+type File struct {
+	Path    string
+	Options []Option
+}
+
+// Arguments is an ffmpeg argument list split into its options, inputs and outputs
+type Arguments struct {
+	Options []Option
+	Inputs  []File
+	Outputs []File
+}
+
+// options not taking a value, every other option consumes the following argument
+var flagOptions = map[string]bool{
+	"y": true, "n": true, "stats": true, "nostats": true, "stdin": true, "nostdin": true, "hide_banner": true,
+	"copyts": true, "start_at_zero": true, "shortest": true, "re": true, "an": true, "vn": true, "sn": true, "dn": true,
+	"ignore_unknown": true, "copy_unknown": true, "benchmark": true, "benchmark_all": true, "debug_ts": true,
+	"xerror": true, "accurate_seek": true, "autorotate": true, "autoscale": true, "seek_timestamp": true,
+	"copyinkf": true, "fix_sub_duration": true, "find_stream_info": true, "bitexact": true, "vstats": true,
+	"dump": true, "hex": true, "psnr": true, "qphist": true, "print_graphs": true, "report": true, "version": true,
+	"buildconf": true, "formats": true, "muxers": true, "demuxers": true, "devices": true, "codecs": true,
+	"decoders": true, "encoders": true, "bsfs": true, "protocols": true, "filters": true, "pix_fmts": true,
+	"layouts": true, "sample_fmts": true, "dispositions": true, "colors": true, "hwaccels": true, "L": true,
+}
+
+// options whose value is a path ffmpeg reads from (true) or writes to (false)
+var pathOptions = map[string]bool{
+	"attach": true, "filter_script": true, "filter_complex_script": true,
+	"progress": false, "vstats_file": false, "passlogfile": false, "dump_attachment": false, "sdp_file": false,
+	"stats_enc_pre": false, "stats_enc_post": false, "stats_mux_pre": false,
+	"hls_segment_filename": false, "segment_list": false,
+}
+
+// options naming a file the muxer writes next to its output
+var siblingOptions = map[string]bool{
+	"master_pl_name": true,
+}
+
+// options carrying a filtergraph
+var filterOptions = map[string]bool{
+	"vf": true, "af": true, "filter": true, "filter_complex": true, "lavfi": true,
+}
+
+// SplitCommand splits a command into its arguments like a shell would
+func SplitCommand(command string) ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return shellwordsUnicodeSafe(command)
+	}
+	return shellwords.NewParser().Parse(command)
+}
+
+// ParseArguments splits an ffmpeg argument list into options, inputs and outputs.
+// Options in front of an input or output belong to it, options following the last output are global.
+func ParseArguments(args []string) (*Arguments, error) {
+	a := &Arguments{Options: []Option{}, Inputs: []File{}, Outputs: []File{}}
+	pending := []Option{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			a.Outputs = append(a.Outputs, File{Path: arg, Options: pending})
+			pending = []Option{}
+			continue
+		}
+
+		name := arg[1:]
+		if name == "i" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("missing input after '-i'")
+			}
+			i++
+			a.Inputs = append(a.Inputs, File{Path: args[i], Options: pending})
+			pending = []Option{}
+			continue
+		}
+
+		option := Option{Name: name}
+		if !isFlag(name) {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("missing value for option '%s'", arg)
+			}
+			i++
+			option.Value = args[i]
+		}
+		a.Options = append(a.Options, option)
+		pending = append(pending, option)
+	}
+	return a, nil
+}
+
+// BaseName returns the option name without its stream specifier (e.g. "c" for "c:v")
+func (o Option) BaseName() string {
+	name := strings.TrimPrefix(o.Name, "/")
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// IsPath reports whether the option value is a file ffmpeg accesses, a leading slash loads any option value from a file
+func (o Option) IsPath() bool {
+	_, ok := pathOptions[o.BaseName()]
+	return ok || strings.HasPrefix(o.Name, "/")
+}
+
+// Reads reports whether ffmpeg reads the file of a path option
+func (o Option) Reads() bool {
+	return pathOptions[o.BaseName()] || strings.HasPrefix(o.Name, "/")
+}
+
+// IsFilter reports whether the option value is a filtergraph
+func (o Option) IsFilter() bool {
+	return filterOptions[o.BaseName()] && !strings.HasPrefix(o.Name, "/")
+}
+
+// Format returns the value of the format option (-f) preceding the file
+func (f File) Format() string {
+	format := ""
+	for _, o := range f.Options {
+		if o.Name == "f" {
+			format = o.Value
+		}
+	}
+	return format
+}
+
+func isFlag(name string) bool {
+	if flagOptions[name] {
+		return true
+	}
+	// boolean options can be negated with a "no" prefix (e.g. -noautorotate)
+	return strings.HasPrefix(name, "no") && flagOptions[name[2:]]
+}
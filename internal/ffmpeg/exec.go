@@ -8,9 +8,7 @@ import (
 	"math"
 	"os/exec"
 	"regexp"
-	"runtime"
 
-	"github.com/mattn/go-shellwords"
 	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/yosev/debugo"
 )
@@ -19,16 +17,14 @@ var debug = debugo.New("ffmpeg")
 
 // ExecuteFFmpeg runs the ffmpeg command, provides progress updates, and checks the result
 func Execute(request *ExecutionRequest) error {
-	var args []string
-	var err error
-	if runtime.GOOS == "windows" {
-		args, err = shellwordsUnicodeSafe(request.Command)
-	} else {
-		args, err = shellwords.NewParser().Parse(request.Command)
-	}
+	args, err := SplitCommand(request.Command)
 	if err != nil {
 		return fmt.Errorf("FFMPEG - failed to parse command: %v", err)
 	}
+	// wildcards may resolve to anything, so the policy is enforced again on the final arguments
+	if err := CheckArguments(args); err != nil {
+		return fmt.Errorf("FFMPEG - command rejected: %v", err)
+	}
 	args = append(args, "-progress", "pipe:2")
 	cmd := exec.CommandContext(request.Ctx, config.Config().FFMpeg, args...)
 
@@ -91,7 +87,7 @@ func Execute(request *ExecutionRequest) error {
 		return errors.New(stderr)
 	}
 
-	fmt.Sprintf("last line: %s", lastLine)
+	debug.Debugf("last line: %s (uuid: %s)", lastLine, request.Task.Uuid)
 
 	return nil
 }
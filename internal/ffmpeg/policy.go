@@ -0,0 +1,282 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// The command policy is enforced twice: when a task or preset is submitted (ValidateCommand) and
+// again on the resolved command right before ffmpeg is started. On submission wildcards are not
+// resolved yet, so files are only checked as far as they are known (see sandbox.Validate*).
+// Empty allow lists permit everything, denied entries always win.
+
+// ValidateCommand checks an unresolved command against the configured policy
+func ValidateCommand(command string) error {
+	if command == "" {
+		return nil
+	}
+	// wildcards may contain spaces, so they are masked before the command is split
+	args, err := SplitCommand(wildcards.Mask(command))
+	if err != nil {
+		return fmt.Errorf("failed to parse command: %v", err)
+	}
+	return enforce(args, false)
+}
+
+// CheckArguments checks the arguments of a resolved command against the configured policy
+func CheckArguments(args []string) error {
+	return enforce(args, true)
+}
+
+// CheckInput checks the protocols of a resolved input file and whether it is inside the input roots
+func CheckInput(path string) error {
+	return checkTarget("input", path, true, sandbox.ValidateInput, sandbox.CheckInput)
+}
+
+// CheckOutput checks the protocols of a resolved output file and whether it is inside the output roots
+func CheckOutput(path string) error {
+	return checkTarget("output", path, true, sandbox.ValidateOutput, sandbox.CheckOutput)
+}
+
+func enforce(args []string, resolved bool) error {
+	a, err := ParseArguments(args)
+	if err != nil {
+		return err
+	}
+	c := config.Config()
+
+	for _, o := range a.Options {
+		if !optionPermitted(o, c.AllowedOptions, c.DeniedOptions) {
+			return fmt.Errorf("option '-%s' is not allowed", o.Name)
+		}
+		if o.IsFilter() {
+			if err := checkFilters(o.Value, c.AllowedFilters, c.DeniedFilters); err != nil {
+				return fmt.Errorf("%v (option '-%s')", err, o.Name)
+			}
+		}
+		if o.IsPath() {
+			validate, check := sandbox.ValidateOutput, sandbox.CheckOutput
+			if o.Reads() {
+				validate, check = sandbox.ValidateInput, sandbox.CheckInput
+			}
+			if err := checkTarget("option '-"+o.Name+"'", o.Value, resolved, validate, check); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, in := range a.Inputs {
+		// the lavfi device takes a filtergraph as input
+		if in.Format() == "lavfi" {
+			if err := checkFilters(in.Path, c.AllowedFilters, c.DeniedFilters); err != nil {
+				return fmt.Errorf("%v (input '%s')", err, in.Path)
+			}
+			continue
+		}
+		if err := checkTarget("input", in.Path, resolved, sandbox.ValidateInput, sandbox.CheckInput); err != nil {
+			return err
+		}
+	}
+	for _, out := range a.Outputs {
+		targets := []string{out.Path}
+		// the tee muxer writes to every output listed in its path
+		if out.Format() == "tee" {
+			targets = teeOutputs(out.Path)
+		}
+		for _, target := range targets {
+			if err := checkTarget("output", target, resolved, sandbox.ValidateOutput, sandbox.CheckOutput); err != nil {
+				return err
+			}
+		}
+
+		for _, o := range out.Options {
+			// the directory of the output is not known before its wildcards are resolved
+			if !siblingOptions[o.BaseName()] || (!resolved && strings.Contains(out.Path, "${")) {
+				continue
+			}
+			sibling := filepath.Join(filepath.Dir(out.Path), o.Value)
+			if err := checkTarget("option '-"+o.Name+"'", sibling, resolved, sandbox.ValidateOutput, sandbox.CheckOutput); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// teeOutputs returns the outputs of a tee muxer path like "[f=mpegts]udp://host:1234|[f=mp4]out.mp4"
+// without their options
+func teeOutputs(path string) []string {
+	outputs := []string{}
+	for _, slave := range strings.Split(path, "|") {
+		if strings.HasPrefix(slave, "[") {
+			if i := strings.Index(slave, "]"); i >= 0 {
+				slave = slave[i+1:]
+			}
+		}
+		outputs = append(outputs, slave)
+	}
+	return outputs
+}
+
+// checkTarget checks the protocols of an input or output, local files additionally have to be inside the sandbox
+func checkTarget(kind string, target string, resolved bool, validate func(string) error, check func(string) error) error {
+	c := config.Config()
+	protocols, files := splitProtocols(target)
+	for _, protocol := range protocols {
+		if !permitted(protocol, c.AllowedProtocols, c.DeniedProtocols) {
+			return fmt.Errorf("protocol '%s' is not allowed (%s '%s')", protocol, kind, target)
+		}
+	}
+	for _, file := range files {
+		var err error
+		if resolved {
+			err = check(file)
+		} else {
+			err = validate(file)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protocols wrapping other urls, e.g. cache:http://... or concat:a.ts|b.ts
+var nestingProtocols = map[string]bool{
+	"async": true, "cache": true, "crypto": true, "subfile": true, "concat": true,
+}
+
+const schemeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+-."
+
+// splitProtocols returns all protocols a target is accessed with and the local files among them,
+// it follows the rules ffmpeg uses to detect the protocol of a url
+func splitProtocols(target string) (protocols []string, files []string) {
+	if target == "-" {
+		return []string{"pipe"}, nil
+	}
+
+	n := 0
+	for n < len(target) && strings.IndexByte(schemeChars, target[n]) >= 0 {
+		n++
+	}
+	scheme := strings.ToLower(target[:n])
+	// a single letter is a windows drive (C:\...)
+	isProtocol := n > 1 && n < len(target) && (target[n] == ':' || (scheme == "subfile" && target[n] == ',' && strings.Contains(target[n:], ":")))
+	if !isProtocol {
+		return []string{"file"}, []string{target}
+	}
+
+	// combined protocols like crypto+http
+	protocols = strings.Split(scheme, "+")
+	rest := target[n+1:]
+	if scheme == "subfile" {
+		rest = target[strings.Index(target, ":")+1:]
+	}
+	switch {
+	case scheme == "file":
+		files = append(files, rest)
+	case scheme == "concat":
+		for _, part := range strings.Split(rest, "|") {
+			p, f := splitProtocols(part)
+			protocols = append(protocols, p...)
+			files = append(files, f...)
+		}
+	case nestingProtocols[scheme]:
+		p, f := splitProtocols(rest)
+		protocols = append(protocols, p...)
+		files = append(files, f...)
+	}
+	return protocols, files
+}
+
+// checkFilters checks every filter of a filtergraph
+func checkFilters(graph string, allow []string, deny []string) error {
+	for _, filter := range filterNames(graph) {
+		if !permitted(filter, allow, deny) {
+			return fmt.Errorf("filter '%s' is not allowed", filter)
+		}
+	}
+	return nil
+}
+
+// filterNames returns the names of all filters of a filtergraph, ignoring labels, instance names and arguments
+func filterNames(graph string) []string {
+	names := []string{}
+	for _, filter := range splitGraph(graph) {
+		filter = strings.TrimSpace(filter)
+		// strip leading input labels ([in])
+		for strings.HasPrefix(filter, "[") {
+			end := strings.Index(filter, "]")
+			if end < 0 {
+				break
+			}
+			filter = strings.TrimSpace(filter[end+1:])
+		}
+		end := strings.IndexAny(filter, "=@[ \t\n")
+		if end >= 0 {
+			filter = filter[:end]
+		}
+		if filter == "" || filter == "sws_flags" {
+			continue
+		}
+		names = append(names, filter)
+	}
+	return names
+}
+
+// splitGraph splits a filtergraph at ',' and ';' outside of quotes and escapes
+func splitGraph(graph string) []string {
+	parts := []string{}
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(graph); i++ {
+		ch := graph[i]
+		switch {
+		case ch == '\\' && i+1 < len(graph):
+			current.WriteByte(ch)
+			current.WriteByte(graph[i+1])
+			i++
+		case ch == '\'':
+			quoted = !quoted
+			current.WriteByte(ch)
+		case (ch == ',' || ch == ';') && !quoted:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	return append(parts, current.String())
+}
+
+// optionPermitted checks an option by its full name and by its name without stream specifier,
+// so denying "c" also denies "c:v"
+func optionPermitted(o Option, allow []string, deny []string) bool {
+	name := strings.TrimPrefix(o.Name, "/")
+	if contains(deny, name) || contains(deny, o.BaseName()) {
+		return false
+	}
+	return len(allow) == 0 || contains(allow, name) || contains(allow, o.BaseName())
+}
+
+// permitted checks a name against an allow and a deny list, list entries may be written with a leading dash
+func permitted(name string, allow []string, deny []string) bool {
+	if contains(deny, name) {
+		return false
+	}
+	return len(allow) == 0 || contains(allow, name)
+}
+
+func contains(list []string, name string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(entry), "-"), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+func TestParseArguments(t *testing.T) {
+	a, err := ParseArguments([]string{"-y", "-ss", "10", "-i", "in.mov", "-c:v", "libx264", "-an", "out.mp4", "-f", "null", "-"})
+	if err != nil {
+		t.Fatalf("Failed to parse arguments: %v", err)
+	}
+	if len(a.Inputs) != 1 || a.Inputs[0].Path != "in.mov" || len(a.Inputs[0].Options) != 2 {
+		t.Errorf("Unexpected inputs %+v", a.Inputs)
+	}
+	if len(a.Outputs) != 2 || a.Outputs[0].Path != "out.mp4" || a.Outputs[1].Path != "-" || a.Outputs[1].Format() != "null" {
+		t.Errorf("Unexpected outputs %+v", a.Outputs)
+	}
+	if len(a.Options) != 5 || a.Options[2].Name != "c:v" || a.Options[2].BaseName() != "c" || a.Options[2].Value != "libx264" {
+		t.Errorf("Unexpected options %+v", a.Options)
+	}
+
+	if _, err := ParseArguments([]string{"-i", "in.mov", "-c:v"}); err == nil {
+		t.Error("Expected missing option value to fail")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	viper.Set("deniedOptions", []string{"-dump_attachment"})
+	viper.Set("allowedProtocols", []string{"file", "pipe", "https"})
+	viper.Set("deniedFilters", []string{"movie", "amovie"})
+	config.Init()
+	defer func() {
+		viper.Set("deniedOptions", []string{})
+		viper.Set("allowedProtocols", []string{})
+		viper.Set("deniedFilters", []string{})
+		config.Init()
+	}()
+
+	tests := []struct {
+		name    string
+		command string
+		valid   bool
+	}{
+		{name: "Plain transcode", command: "-y -i ${INPUT_FILE} -c:v libx264 -vf 'scale=1280:-2,fps=25' ${OUTPUT_FILE}", valid: true},
+		{name: "Wildcard with spaces", command: "-i ${INPUT_FILE} -metadata title=${metadata.show | default 'a b'} ${OUTPUT_FILE}", valid: true},
+		{name: "Allowed protocol", command: "-i https://example.com/in.mp4 out.mp4", valid: true},
+		{name: "Null output", command: "-i in.mov -f null -", valid: true},
+		{name: "Denied option", command: "-dump_attachment:t out.ttf -i in.mkv", valid: false},
+		{name: "Protocol not allowed", command: "-i in.mov -f mpegts udp://10.0.0.1:1234", valid: false},
+		{name: "Nested protocol", command: "-i cache:http://example.com/in.mp4 out.mp4", valid: false},
+		{name: "Concat protocol", command: "-i concat:a.ts|ftp://example.com/b.ts out.mp4", valid: false},
+		{name: "Denied filter", command: "-i in.mov -filter_complex '[0:v]scale=640:-2[s];movie=/etc/passwd[m];[s][m]overlay' out.mp4", valid: false},
+		{name: "Denied filter in lavfi input", command: "-f lavfi -i amovie=/etc/passwd out.wav", valid: false},
+		{name: "Escaped separator", command: "-i in.mov -vf drawtext=text='a\\,movie' out.mp4", valid: true},
+		{name: "Progress protocol", command: "-i in.mov -progress tcp://10.0.0.1:1234 out.mp4", valid: false},
+		{name: "Unbalanced quote", command: "-i 'in.mov out.mp4", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCommand(tt.command)
+			if tt.valid && err != nil {
+				t.Errorf("Expected '%s' to be valid, got %v", tt.command, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected '%s' to be rejected", tt.command)
+			}
+		})
+	}
+}
+
+func TestPolicyPaths(t *testing.T) {
+	out := t.TempDir()
+	viper.Set("outputRoots", []string{out})
+	viper.Set("deniedProtocols", []string{"udp"})
+	config.Init()
+	defer func() {
+		viper.Set("outputRoots", []string{})
+		viper.Set("deniedProtocols", []string{})
+		config.Init()
+	}()
+
+	tests := []struct {
+		name    string
+		command string
+		valid   bool
+	}{
+		{name: "Segment filename inside root", command: "-i in.mov -hls_segment_filename " + out + "/seg_%03d.ts " + out + "/index.m3u8", valid: true},
+		{name: "Segment filename outside root", command: "-i in.mov -hls_segment_filename /etc/seg_%03d.ts " + out + "/index.m3u8", valid: false},
+		{name: "Segment list outside root", command: "-i in.mov -f segment -segment_list /etc/list.m3u8 " + out + "/seg_%03d.ts", valid: false},
+		{name: "Master playlist next to output", command: "-i in.mov -master_pl_name master.m3u8 " + out + "/index.m3u8", valid: true},
+		{name: "Master playlist outside root", command: "-i in.mov -master_pl_name ../../etc/master.m3u8 " + out + "/index.m3u8", valid: false},
+		{name: "Master playlist of unresolved output", command: "-i in.mov -master_pl_name master.m3u8 ${OUTPUT_FILE}", valid: true},
+		{name: "Tee outputs inside root", command: "-i in.mov -f tee '[f=mp4]" + out + "/a.mp4|[f=mpegts]" + out + "/a.ts'", valid: true},
+		{name: "Tee output outside root", command: "-i in.mov -f tee '[f=mp4]" + out + "/a.mp4|[f=mpegts]/etc/a.ts'", valid: false},
+		{name: "Tee output with denied protocol", command: "-i in.mov -f tee '[f=mp4]" + out + "/a.mp4|[f=mpegts]udp://10.0.0.1:1234'", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCommand(tt.command)
+			if tt.valid && err != nil {
+				t.Errorf("Expected '%s' to be valid, got %v", tt.command, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected '%s' to be rejected", tt.command)
+			}
+		})
+	}
+
+	if err := CheckArguments([]string{"-i", "in.mov", "-master_pl_name", "../master.m3u8", out + "/index.m3u8"}); err == nil {
+		t.Error("Expected resolved master playlist outside root to be rejected")
+	}
+}
+
+func TestSplitProtocols(t *testing.T) {
+	tests := []struct {
+		target    string
+		protocols string
+		files     string
+	}{
+		{target: "/data/in.mov", protocols: "file", files: "/data/in.mov"},
+		{target: "C:\\data\\in.mov", protocols: "file", files: "C:\\data\\in.mov"},
+		{target: "file:/data/in.mov", protocols: "file", files: "/data/in.mov"},
+		{target: "crypto+https://example.com/a.ts", protocols: "crypto,https", files: ""},
+		{target: "async:cache:http://example.com/a.ts", protocols: "async,cache,http", files: ""},
+		{target: "subfile,,start,0,end,100,,:/dvd/a.vob", protocols: "subfile,file", files: "/dvd/a.vob"},
+	}
+
+	for _, tt := range tests {
+		protocols, files := splitProtocols(tt.target)
+		if strings.Join(protocols, ",") != tt.protocols || strings.Join(files, ",") != tt.files {
+			t.Errorf("%s: got protocols %v and files %v", tt.target, protocols, files)
+		}
+	}
+}
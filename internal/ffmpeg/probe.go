@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+// Probe runs ffprobe on a file and returns its format and streams. The first video and audio stream
+// are additionally available as "video" and "audio", the duration in seconds as "duration".
+func Probe(ctx context.Context, path string) (map[string]any, error) {
+	out, err := exec.CommandContext(ctx, config.Config().FFProbe, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("FFPROBE - %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("FFPROBE - failed to run ffprobe: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("FFPROBE - failed to parse output: %v", err)
+	}
+
+	if format, ok := result["format"].(map[string]any); ok {
+		if duration, err := strconv.ParseFloat(fmt.Sprint(format["duration"]), 64); err == nil {
+			result["duration"] = duration
+		}
+	}
+	streams, _ := result["streams"].([]any)
+	for _, s := range streams {
+		stream, ok := s.(map[string]any)
+		if !ok {
+			continue
+		}
+		codecType, _ := stream["codec_type"].(string)
+		if (codecType == "video" || codecType == "audio") && result[codecType] == nil {
+			if rate, ok := stream["avg_frame_rate"].(string); ok {
+				stream["fps"] = frameRate(rate)
+			}
+			result[codecType] = stream
+		}
+	}
+	return result, nil
+}
+
+// frameRate converts a rational frame rate (e.g. 30000/1001) to frames per second
+func frameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,28 @@
+package interceptor
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+// Permission rejects callers lacking the given permission, it has no effect if authentication is disabled
+func Permission(permission dto.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.Config().Auth {
+			c.Next()
+			return
+		}
+
+		auth, ok := c.Get("auth")
+		if !ok || !auth.(*dto.Auth).Can(permission) {
+			c.JSON(403, exceptions.HttpForbidden(fmt.Errorf("missing permission '%s'", permission), "https://docs.ffmate.io/docs/authentication#roles"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package library
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed presets.yaml
+var presetsYaml []byte
+
+var presets []dto.LibraryPreset
+
+func init() {
+	var raw any
+	if err := yaml.Unmarshal(presetsYaml, &raw); err != nil {
+		panic(fmt.Sprintf("invalid preset library: %v", err))
+	}
+	// yaml is converted to json, so the json field names of the presets apply
+	b, err := json.Marshal(raw)
+	if err != nil {
+		panic(fmt.Sprintf("invalid preset library: %v", err))
+	}
+	var library struct {
+		Presets []dto.LibraryPreset `json:"presets"`
+	}
+	if err := json.Unmarshal(b, &library); err != nil {
+		panic(fmt.Sprintf("invalid preset library: %v", err))
+	}
+	presets = library.Presets
+}
+
+// Presets returns a copy of all presets of the library
+func Presets() []dto.LibraryPreset {
+	return append([]dto.LibraryPreset{}, presets...)
+}
+
+// Find returns a copy of the library preset with the given id
+func Find(id string) (*dto.LibraryPreset, error) {
+	for _, p := range presets {
+		if p.Id == id {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("library preset '%s' not found", id)
+}
@@ -0,0 +1,49 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+)
+
+func TestPresets(t *testing.T) {
+	ids := map[string]bool{}
+	for _, p := range Presets() {
+		if p.Id == "" || p.Version < 1 || p.Name == "" || p.Command == "" {
+			t.Errorf("Library preset requires an id, a version, a name and a command, got %+v", p)
+		}
+		if ids[p.Id] {
+			t.Errorf("Duplicate library preset id %s", p.Id)
+		}
+		ids[p.Id] = true
+	}
+	if len(ids) == 0 {
+		t.Error("Expected library presets")
+	}
+
+	if _, err := Find("h264-web"); err != nil {
+		t.Errorf("Failed to find library preset: %v", err)
+	}
+	if _, err := Find("missing"); err == nil {
+		t.Error("Expected error when finding missing library preset")
+	}
+}
+
+func TestPresetCommands(t *testing.T) {
+	// commands are split like a shell would, unquoted separators like ';' cut them short
+	for _, p := range Presets() {
+		args, err := ffmpeg.SplitCommand(p.Command)
+		if err != nil {
+			t.Errorf("Failed to split command of library preset %s: %v", p.Id, err)
+			continue
+		}
+		parsed, err := ffmpeg.ParseArguments(args)
+		if err != nil {
+			t.Errorf("Failed to parse command of library preset %s: %v", p.Id, err)
+			continue
+		}
+		if len(parsed.Outputs) != 1 || parsed.Outputs[0].Path != "${OUTPUT_FILE}" {
+			t.Errorf("Expected library preset %s to have exactly one output, got %+v", p.Id, parsed.Outputs)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package internal
 
 import (
 	"embed"
+	"slices"
 
 	"github.com/gin-contrib/cors"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/configdir"
 	"github.com/welovemedia/ffmate/internal/controller"
 	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
 	"github.com/welovemedia/ffmate/internal/metrics"
 	"github.com/welovemedia/ffmate/internal/middleware"
 	"github.com/welovemedia/ffmate/internal/queue"
@@ -18,19 +23,28 @@ var prefix = "/api"
 
 func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	// setup cors
+	origins := config.Config().CorsOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
 	s.Gin().Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"*"},
-		AllowCredentials: true,
+		AllowOrigins:  origins,
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:  []string{"*"},
+		ExposeHeaders: []string{"*"},
+		// credentials must never be combined with a wildcard origin
+		AllowCredentials: !slices.Contains(origins, "*"),
 	}))
 
 	// setup repositories
 	(&repository.Task{DB: s.DB()}).Setup()
 	(&repository.Webhook{DB: s.DB()}).Setup()
+	(&repository.WebhookDelivery{DB: s.DB()}).Setup()
 	(&repository.Preset{DB: s.DB()}).Setup()
 	(&repository.Watchfolder{DB: s.DB()}).Setup()
+	(&repository.ApiKey{DB: s.DB()}).Setup()
+	(&repository.User{DB: s.DB()}).Setup()
+	(&repository.Audit{DB: s.DB()}).Setup()
 
 	// setup metrics
 	metrics := &metrics.Metrics{}
@@ -45,6 +59,7 @@ func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	s.RegisterMiddleware("404", middleware.E404)
 	s.RegisterMiddleware("debugo", middleware.Debugo)
 	s.RegisterMiddleware("version", middleware.Version)
+	s.RegisterMiddleware("auth", middleware.Auth)
 
 	// setup services
 	service.Init(s)
@@ -61,6 +76,13 @@ func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	s.RegisterController(&controller.UmamiController{Prefix: prefix})
 	s.RegisterController(&controller.AIController{Prefix: prefix})
 	s.RegisterController(&controller.ClientController{Prefix: prefix})
+	s.RegisterController(&controller.AuthController{Prefix: prefix})
+	s.RegisterController(&controller.UserController{Prefix: prefix})
+	s.RegisterController(&controller.AuditController{Prefix: prefix})
+	s.RegisterController(&controller.BundleController{Prefix: prefix})
+
+	// metrics are registered after the middlewares so they are protected by authentication
+	s.RegisterMetrics(interceptor.Permission(dto.PERMISSION_READ))
 
 	// Initialize queue processor
 	(&queue.Queue{
@@ -72,4 +94,11 @@ func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	(&watchfolder.Watchfolder{
 		Sev:                   s,
 		WatchfolderRepository: &repository.Watchfolder{DB: s.DB()}}).Init()
+
+	// Apply the config directory after the watchfolder processor picks up created watchfolders
+	if config.Config().ConfigDir != "" {
+		(&configdir.ConfigDir{
+			Sev:  s,
+			Path: config.Config().ConfigDir}).Init()
+	}
 }
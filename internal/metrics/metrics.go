@@ -21,11 +21,13 @@ var gauges = map[string]prometheus.Gauge{
 	"webhook.created":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "webhook_created", Help: "Number of created webhooks"}),
 	"webhook.executed": prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "webhook_executed", Help: "Number of executed webhooks"}),
 	"webhook.deleted":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "webhook_deleted", Help: "Number of deleted webhooks"}),
+	"webhook.failed":   prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "webhook_failed", Help: "Number of failed webhook deliveries"}),
 
 	"watchfolder.created":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_created", Help: "Number of created watchfolders"}),
 	"watchfolder.executed": prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_executed", Help: "Number of executed watchfolders"}),
 	"watchfolder.updated":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_updated", Help: "Number of updated watchfolder"}),
 	"watchfolder.deleted":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_deleted", Help: "Number of deleted watchfolders"}),
+	"watchfolder.error":    prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_error", Help: "Number of watchfolder errors"}),
 }
 
 var gaugesVec = map[string]*prometheus.GaugeVec{
@@ -61,6 +63,46 @@ var gaugesVec = map[string]*prometheus.GaugeVec{
 		},
 		[]string{"sidecarPath", "scriptPath"},
 	),
+	"watchfolder.health": prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchfolder_health",
+			Help:      "Health of a watchfolder (0: OK, 1: DEGRADED, 2: FAILING)",
+		},
+		[]string{"uuid"},
+	),
+	"watchfolder.consecutiveFailures": prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchfolder_consecutive_failures",
+			Help:      "Number of consecutive failed scans of a watchfolder",
+		},
+		[]string{"uuid"},
+	),
+	"watchfolder.lastSuccessfulScan": prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchfolder_last_successful_scan",
+			Help:      "Unix timestamp of the last successful scan of a watchfolder",
+		},
+		[]string{"uuid"},
+	),
+	"watchfolder.filesSeen": prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchfolder_files_seen",
+			Help:      "Number of files seen by a watchfolder",
+		},
+		[]string{"uuid"},
+	),
+	"watchfolder.filesIngested": prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "watchfolder_files_ingested",
+			Help:      "Number of files a watchfolder created tasks for",
+		},
+		[]string{"uuid"},
+	),
 	"preset.global": prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
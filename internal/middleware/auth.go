@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+// paths that are reachable without credentials
+var publicPaths = []string{"/api/v1/auth/login", "/api/v1/version"}
+
+// Auth authenticates requests against the api, metrics and websocket by api key or session token.
+// The token is read from the Authorization header ("Bearer <token>") or, for websockets, the token query string.
+func Auth(c *gin.Context, s *sev.Sev) {
+	if !config.Config().Auth || !requiresAuth(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		c.AbortWithStatusJSON(401, exceptions.HttpUnauthorized(errors.New("missing api key or session token"), "https://docs.ffmate.io/docs/authentication"))
+		return
+	}
+
+	auth, err := service.AuthService().Authenticate(token)
+	if err != nil {
+		c.AbortWithStatusJSON(401, exceptions.HttpUnauthorized(err, "https://docs.ffmate.io/docs/authentication"))
+		return
+	}
+
+	c.Set("auth", auth)
+	c.Next()
+}
+
+func requiresAuth(path string) bool {
+	for _, p := range publicPaths {
+		if path == p {
+			return false
+		}
+	}
+	return strings.HasPrefix(path, "/api/") || path == "/metrics" || strings.HasPrefix(path, "/metrics/")
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Task{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.ApiKey{}, &model.Session{}, &model.User{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	s := sev.New("test", "", "", 3000)
+	s.SetDB(db)
+	service.Init(s)
+
+	s.RegisterMiddleware("auth", Auth)
+	s.Gin().GET("/api/v1/tasks", interceptor.Permission(dto.PERMISSION_READ), func(c *gin.Context) { c.Status(200) })
+	s.Gin().POST("/api/v1/presets", interceptor.Permission(dto.PERMISSION_PRESET_WRITE), func(c *gin.Context) { c.Status(200) })
+	s.Gin().GET("/api/v1/version", func(c *gin.Context) { c.Status(200) })
+
+	readKey, _, err := service.AuthService().CreateApiKey(&dto.NewApiKey{Name: "read", Scopes: []dto.Scope{dto.SCOPE_READ}})
+	if err != nil {
+		t.Fatalf("Failed to create api key: %v", err)
+	}
+	adminKey, _, _ := service.AuthService().CreateApiKey(&dto.NewApiKey{Name: "admin", Scopes: []dto.Scope{dto.SCOPE_ADMIN}})
+	session, err := service.AuthService().Login(&dto.Login{ApiKey: readKey})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+	if _, err := service.AuthService().CreateUser(&dto.NewUser{Username: "operator", Password: "operator-password", Role: dto.ROLE_OPERATOR}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	userSession, err := service.AuthService().Login(&dto.Login{Username: "operator", Password: "operator-password"})
+	if err != nil {
+		t.Fatalf("Failed to login user: %v", err)
+	}
+	if _, err := service.AuthService().Login(&dto.Login{Username: "operator", Password: "wrong-password"}); err == nil {
+		t.Error("Expected login with wrong password to fail")
+	}
+
+	tests := []struct {
+		name           string
+		auth           bool
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"Disabled", false, "POST", "/api/v1/presets", "", 200},
+		{"Missing token", true, "GET", "/api/v1/tasks", "", 401},
+		{"Invalid token", true, "GET", "/api/v1/tasks", "ffm_invalid", 401},
+		{"Public path", true, "GET", "/api/v1/version", "", 200},
+		{"Read scope", true, "GET", "/api/v1/tasks", readKey, 200},
+		{"Missing scope", true, "POST", "/api/v1/presets", readKey, 403},
+		{"Implied scope", true, "GET", "/api/v1/tasks", adminKey, 200},
+		{"Admin scope", true, "POST", "/api/v1/presets", adminKey, 200},
+		{"Session token", true, "GET", "/api/v1/tasks", session.Token, 200},
+		{"Session query", true, "GET", "/api/v1/tasks?token=" + session.Token, "", 200},
+		{"User role", true, "GET", "/api/v1/tasks", userSession.Token, 200},
+		{"Missing role permission", true, "POST", "/api/v1/presets", userSession.Token, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("auth", tt.auth)
+			config.Init()
+			defer func() {
+				viper.Set("auth", false)
+				config.Init()
+			}()
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			s.Gin().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	t.Run("Revoked key", func(t *testing.T) {
+		keys, _ := service.AuthService().ListApiKeys()
+		for _, key := range *keys {
+			if key.Name == "read" {
+				service.AuthService().RevokeApiKey(key.Uuid)
+			}
+		}
+
+		for _, token := range []string{readKey, session.Token} {
+			if _, err := service.AuthService().Authenticate(token); err == nil {
+				t.Error("Expected revoked key and its sessions to be rejected")
+			}
+		}
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/ffmpeg"
 	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
 	"github.com/welovemedia/ffmate/internal/utils/wildcards"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/yosev/debugo"
@@ -76,22 +77,64 @@ func (q *Queue) Init() {
 
 func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func()) {
 	defer doneFunc()
+	// a panic while resolving or processing fails the task instead of the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			q.failTask(task, fmt.Errorf("task panicked: %v", r))
+		}
+	}()
 
 	task.StartedAt = time.Now().UnixMilli()
+	task.Status = dto.RUNNING
+	if hasProcessing(task.PreProcessing) {
+		task.Status = dto.PRE_PROCESSING
+	}
+	q.updateTask(task)
 	q.Sev.Logger().Infof("processing task (uuid: %s)", task.Uuid)
+	service.TaskService().FireLifecycle(dto.TASK_STARTED, task)
+
+	// all wildcards of a task resolve from one context, so every template is parsed once
+	wc := service.TaskService().WildcardContext(task, ctx)
 
-	err := q.prePostProcessTask(task, task.PreProcessing, "pre")
+	err := q.prePostProcessTask(ctx, task, task.PreProcessing, "pre", wc)
 	if err != nil {
+		service.TaskService().FireLifecycle(dto.PRE_PROCESSING_FAILED, task)
 		q.failTask(task, fmt.Errorf("PreProcessing failed: %v", err))
 		return
 	}
 
 	// resolve wildcards
-	inFile := wildcards.Replace(task.InputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source)
-	outFile := wildcards.Replace(task.OutputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source)
+	inFile, err := wc.Render(task.InputFile.Raw)
+	if err != nil {
+		q.failTask(task, fmt.Errorf("failed to resolve input file: %v", err))
+		return
+	}
+	outFile, err := wc.Render(task.OutputFile.Raw)
+	if err != nil {
+		q.failTask(task, fmt.Errorf("failed to resolve output file: %v", err))
+		return
+	}
 	task.InputFile.Resolved = inFile
 	task.OutputFile.Resolved = outFile
-	task.Command.Resolved = wildcards.Replace(task.Command.Raw, inFile, outFile, task.Source)
+
+	// wildcards may resolve to any path, so the sandbox and protocol policy are enforced again on the final paths,
+	// before the command is rendered as ${probe.*} runs ffprobe on the input file
+	if err := ffmpeg.CheckInput(inFile); err != nil {
+		q.failTask(task, err)
+		return
+	}
+	if err := ffmpeg.CheckOutput(outFile); err != nil {
+		q.failTask(task, err)
+		return
+	}
+	wc.InputFile = inFile
+	wc.OutputFile = outFile
+	command, err := wc.Render(task.Command.Raw)
+	if err != nil {
+		q.failTask(task, fmt.Errorf("failed to resolve command: %v", err))
+		return
+	}
+	task.Command.Resolved = command
 	task.Status = dto.RUNNING
 	q.updateTask(task)
 
@@ -105,7 +148,7 @@ func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func
 			UpdateFunc: func(progress float64, remaining float64) {
 				task.Progress = progress
 				task.Remaining = remaining
-				q.updateTask(task)
+				service.TaskService().UpdateTaskProgress(task)
 			},
 		},
 	)
@@ -126,8 +169,13 @@ func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func
 
 	q.Sev.Logger().Infof("finished processing (uuid: %s)", task.Uuid)
 
-	err = q.prePostProcessTask(task, task.PostProcessing, "post")
+	err = q.prePostProcessTask(ctx, task, task.PostProcessing, "post", wc)
 	if err != nil {
+		service.TaskService().FireLifecycle(dto.POST_PROCESSING_FAILED, task)
+		if context.Cause(ctx) != nil {
+			q.cancelTask(task, context.Cause(ctx))
+			return
+		}
 		q.failTask(task, fmt.Errorf("PostProcessing failed: %v", err))
 		return
 	}
@@ -135,11 +183,12 @@ func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func
 	task.FinishedAt = time.Now().UnixMilli()
 	task.Status = dto.DONE_SUCCESSFUL
 	q.updateTask(task)
+	service.TaskService().FireLifecycle(dto.TASK_SUCCEEDED, task)
 	q.Sev.Logger().Infof("task successful (uuid: %s)", task.Uuid)
 }
 
-func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProcessing, processorType string) error {
-	if processor != nil && (processor.SidecarPath != nil || processor.ScriptPath != nil) {
+func (q *Queue) prePostProcessTask(ctx context.Context, task *model.Task, processor *dto.PrePostProcessing, processorType string, wc *wildcards.Context) error {
+	if hasProcessing(processor) {
 		if processorType == "pre" {
 			q.Sev.Metrics().GaugeVec("task.preProcessing").WithLabelValues(strconv.FormatBool(processor.SidecarPath != nil && processor.SidecarPath.Raw == ""), strconv.FormatBool(processor.ScriptPath != nil && processor.ScriptPath.Raw == "")).Inc()
 		} else {
@@ -158,13 +207,15 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 			if err != nil {
 				q.Sev.Logger().Errorf("failed to marshal task to write sidecar file: %v", err)
 			} else {
-				if processorType == "pre" {
-					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source)
-				} else {
-					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source)
-				}
+				// preProcessing resolves against the raw input and output file, postProcessing against the resolved ones
+				processor.SidecarPath.Resolved, err = wc.Render(processor.SidecarPath.Raw)
 				q.updateTask(task)
-				err = os.WriteFile(processor.SidecarPath.Resolved, b, 0644)
+				if err == nil {
+					err = sandbox.CheckSidecar(processor.SidecarPath.Resolved)
+				}
+				if err == nil {
+					err = os.WriteFile(processor.SidecarPath.Resolved, b, 0644)
+				}
 				if err != nil {
 					processor.Error = fmt.Errorf("failed to write sidecar: %v", err).Error()
 					q.Sev.Logger().Errorf("failed to write sidecar file: %v", err)
@@ -175,16 +226,19 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 		}
 
 		if processor.Error == "" && processor.ScriptPath != nil && processor.ScriptPath.Raw != "" {
-			if processorType == "pre" {
-				processor.ScriptPath.Resolved = wildcards.Replace(processor.ScriptPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source)
-			} else {
-				processor.ScriptPath.Resolved = wildcards.Replace(processor.ScriptPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source)
-			}
+			script, err := wc.Render(processor.ScriptPath.Raw)
+			processor.ScriptPath.Resolved = script
 			q.updateTask(task)
-			args, err := shellwords.NewParser().Parse(processor.ScriptPath.Resolved)
+			var args []string
+			if err == nil {
+				args, err = shellwords.NewParser().Parse(script)
+			}
 			if err != nil {
 				processor.Error = err.Error()
 				q.Sev.Logger().Errorf("failed to parse %sProcessing script (uuid: %s): %v", processorType, task.Uuid, err)
+			} else if err := sandbox.CheckScript(processor.ScriptPath.Resolved); err != nil {
+				processor.Error = err.Error()
+				q.Sev.Logger().Errorf("rejected %sProcessing script (uuid: %s): %v", processorType, task.Uuid, err)
 			} else {
 				cmd := exec.Command(args[0], args[1:]...)
 				debug.Debugf("triggered %sProcessing script (uuid: %s)", processorType, task.Uuid)
@@ -202,6 +256,13 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 			}
 		}
 
+		if processor.Error == "" && processorType == "post" && processor.Upload != nil {
+			if err := q.upload(ctx, task, processor, wc); err != nil {
+				processor.Error = err.Error()
+				q.Sev.Logger().Errorf("failed %sProcessing upload (uuid: %s): %v", processorType, task.Uuid, err)
+			}
+		}
+
 		processor.FinishedAt = time.Now().UnixMilli()
 		if processor.Error != "" {
 			q.Sev.Logger().Infof("finished %sProcessing with error (uuid: %s)", processorType, task.Uuid)
@@ -218,6 +279,7 @@ func (q *Queue) cancelTask(task *model.Task, err error) {
 	task.Status = dto.DONE_CANCELED
 	task.Error = err.Error()
 	q.updateTask(task)
+	service.TaskService().FireLifecycle(dto.TASK_CANCELED, task)
 	q.Sev.Logger().Warnf("task canceled (uuid: %s): %v", task.Uuid, err)
 }
 
@@ -227,9 +289,14 @@ func (q *Queue) failTask(task *model.Task, err error) {
 	task.Status = dto.DONE_ERROR
 	task.Error = err.Error()
 	q.updateTask(task)
+	service.TaskService().FireLifecycle(dto.TASK_FAILED, task)
 	q.Sev.Logger().Warnf("task failed (uuid: %s):\n%v", task.Uuid, err)
 }
 
+func hasProcessing(processor *dto.PrePostProcessing) bool {
+	return processor != nil && (processor.SidecarPath != nil || processor.ScriptPath != nil || processor.Upload != nil)
+}
+
 func (q *Queue) updateTask(task *model.Task) {
 	service.TaskService().UpdateTask(task)
 }
@@ -3,6 +3,11 @@
 package queue
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,7 +29,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Task{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Task{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -91,4 +96,51 @@ func TestQueue(t *testing.T) {
 			t.Error("Expected error message to be set")
 		}
 	})
+
+	t.Run("Fire task started after status change", func(t *testing.T) {
+		statuses := make(chan dto.TaskStatus, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Data dto.Task `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			statuses <- payload.Data.Status
+		}))
+		defer server.Close()
+		service.WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_STARTED, Url: server.URL}, nil)
+
+		task := &model.Task{
+			InputFile:  &dto.RawResolved{Raw: "/test/input.mp4"},
+			OutputFile: &dto.RawResolved{Raw: "/test/output.mp4"},
+			Command:    &dto.RawResolved{Raw: "echo test"},
+			Status:     dto.DONE_CANCELED,
+		}
+		db.Create(task)
+		queue.processTask(task, context.Background(), func() {})
+
+		select {
+		case status := <-statuses:
+			if status != dto.RUNNING {
+				t.Errorf("Expected task.started with status %s, got %s", dto.RUNNING, status)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("Timeout waiting for task.started")
+		}
+	})
+
+	t.Run("Recover from panicking task", func(t *testing.T) {
+		// a task without command makes resolving the wildcards panic
+		task := &model.Task{
+			InputFile:  &dto.RawResolved{Raw: "/test/input.mp4"},
+			OutputFile: &dto.RawResolved{Raw: "/test/output.mp4"},
+			Status:     dto.RUNNING,
+		}
+		db.Create(task)
+
+		queue.processTask(task, context.Background(), func() {})
+
+		if task.Status != dto.DONE_ERROR || !strings.Contains(task.Error, "task panicked") {
+			t.Errorf("Expected task to fail with the panic, got %s (%s)", task.Status, task.Error)
+		}
+	})
 }
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/storage"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// number of upload attempts per file if not configured otherwise
+const defaultUploadRetries = 3
+
+// upload transfers the output file(s) of a task to the configured bucket and stores the object urls on the processor,
+// canceling the task aborts a running upload
+func (q *Queue) upload(ctx context.Context, task *model.Task, processor *dto.PrePostProcessing, wc *wildcards.Context) error {
+	upload := processor.Upload
+
+	files := []string{task.OutputFile.Resolved}
+	if upload.Files != nil && upload.Files.Raw != "" {
+		resolved, err := wc.Render(upload.Files.Raw)
+		if err != nil {
+			return err
+		}
+		upload.Files.Resolved = strings.Trim(resolved, "\"")
+		if err := sandbox.CheckUpload(upload.Files.Resolved); err != nil {
+			return err
+		}
+		matches, err := filepath.Glob(upload.Files.Resolved)
+		if err != nil {
+			return err
+		}
+		files = matches
+	}
+	if len(files) == 0 {
+		return errors.New("no files found to upload")
+	}
+	// matches are checked one by one as they may be symlinks pointing outside the roots
+	for _, file := range files {
+		if err := sandbox.CheckUpload(file); err != nil {
+			return err
+		}
+	}
+
+	key, err := wc.Render(upload.Key.Raw)
+	if err != nil {
+		return err
+	}
+	upload.Key.Resolved = strings.Trim(key, "\"")
+	q.updateTask(task)
+
+	client, err := storage.NewS3(&upload.S3Connection)
+	if err != nil {
+		return err
+	}
+
+	upload.Urls = []string{}
+	for _, file := range files {
+		key := objectKey(upload.Key.Resolved, file, len(files) > 1)
+		u, err := uploadWithRetry(ctx, client, key, file, upload)
+		if err != nil {
+			return fmt.Errorf("failed to upload '%s': %v", file, err)
+		}
+		upload.Urls = append(upload.Urls, u)
+		q.updateTask(task)
+		debug.Debugf("uploaded file (uuid: %s) to %s", task.Uuid, u)
+	}
+
+	return nil
+}
+
+// objectKey uses the key as prefix if it ends with '/', or if several files share the same key
+func objectKey(key string, file string, multiple bool) string {
+	if key == "" {
+		return filepath.Base(file)
+	}
+	if strings.HasSuffix(key, "/") {
+		return key + filepath.Base(file)
+	}
+	if multiple {
+		return path.Join(key, filepath.Base(file))
+	}
+	return key
+}
+
+func uploadWithRetry(ctx context.Context, client *storage.S3, key string, file string, upload *dto.S3Upload) (string, error) {
+	retries := upload.Retries
+	if retries <= 0 {
+		retries = defaultUploadRetries
+	}
+
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		var u string
+		u, err = client.Upload(ctx, key, file, upload.PartSize)
+		if err == nil {
+			return u, nil
+		}
+		debug.Debugf("upload attempt %d/%d for %s failed: %v", attempt, retries, file, err)
+		if attempt < retries {
+			select {
+			case <-ctx.Done():
+				return "", context.Cause(ctx)
+			case <-time.After(time.Duration(attempt*attempt) * time.Second):
+			}
+		}
+	}
+	return "", err
+}
@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/storage"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		file     string
+		multiple bool
+		want     string
+	}{
+		{name: "Explicit key", key: "renditions/show.mp4", file: "/out/show_1080p.mp4", want: "renditions/show.mp4"},
+		{name: "Prefix", key: "renditions/", file: "/out/show_1080p.mp4", want: "renditions/show_1080p.mp4"},
+		{name: "Multiple files", key: "renditions/show", file: "/out/segment_001.ts", multiple: true, want: "renditions/show/segment_001.ts"},
+		{name: "Empty key", key: "", file: "/out/show.mp4", want: "show.mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := objectKey(tt.key, tt.file, tt.multiple); got != tt.want {
+				t.Errorf("objectKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadSandbox(t *testing.T) {
+	out := t.TempDir()
+	os.WriteFile(filepath.Join(out, "show.mp4"), []byte{}, 0644)
+	os.Symlink("/etc/passwd", filepath.Join(out, "passwd.mp4"))
+
+	viper.Set("outputRoots", []string{out})
+	config.Init()
+	defer func() {
+		viper.Set("outputRoots", []string{})
+		config.Init()
+	}()
+
+	tests := []struct {
+		name  string
+		files string
+	}{
+		{name: "Glob outside root", files: "/etc/*"},
+		{name: "Match through symlink", files: filepath.Join(out, "*.mp4")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &model.Task{OutputFile: &dto.RawResolved{Resolved: filepath.Join(out, "show.mp4")}}
+			processor := &dto.PrePostProcessing{Upload: &dto.S3Upload{Files: &dto.RawResolved{Raw: tt.files}, Key: &dto.RawResolved{}}}
+			err := (&Queue{}).upload(context.Background(), task, processor, &wildcards.Context{})
+			if err == nil || !strings.Contains(err.Error(), "outside the allowed directories") {
+				t.Errorf("Expected upload of '%s' to be rejected, got %v", tt.files, err)
+			}
+		})
+	}
+}
+
+func TestUploadCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	var requests atomic.Int32
+	// every request is denied and cancels the task, so only the first attempt is made
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		cancel(context.Canceled)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client, err := storage.NewS3(&dto.S3Connection{Endpoint: u.Host, Region: "us-east-1", Bucket: "media", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "show.mp4")
+	os.WriteFile(file, []byte("video"), 0644)
+
+	start := time.Now()
+	if _, err := uploadWithRetry(ctx, client, "show.mp4", file, &dto.S3Upload{Retries: 3}); err == nil {
+		t.Fatal("Expected canceled upload to fail")
+	}
+	if requests.Load() != 1 || time.Since(start) > time.Second {
+		t.Errorf("Expected retries to stop once the task was canceled, got %d requests in %v", requests.Load(), time.Since(start))
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+// fields changing with every update that would only add noise to the diff
+var auditIgnoredFields = map[string]bool{"createdAt": true, "updatedAt": true, "createdBy": true, "updatedBy": true, "revision": true}
+
+type auditSvc struct {
+	service
+	sev             *sev.Sev
+	auditRepository *repository.Audit
+}
+
+func (s *auditSvc) ListAudit(page int, perPage int, resource string, resourceUuid string) (*[]model.Audit, int64, error) {
+	return s.auditRepository.List(page, perPage, resource, resourceUuid)
+}
+
+// Record stores a change of a resource, before is nil for created and after is nil for deleted resources.
+// Failing to record is logged but never fails the change itself.
+func (s *auditSvc) Record(resource dto.AuditResource, action dto.AuditAction, resourceUuid string, before dto.InterfaceMap, after dto.InterfaceMap, actor *dto.Actor) {
+	audit, err := s.auditRepository.Create(&model.Audit{
+		Resource:     resource,
+		ResourceUuid: resourceUuid,
+		Action:       action,
+		Actor:        actor.GetName(),
+		SourceIp:     actor.GetIp(),
+		Before:       before,
+		After:        after,
+		Changes:      diffAuditState(before, after),
+	})
+	if err != nil {
+		s.sev.Logger().Warnf("failed to record audit entry for %s %s (uuid: %s): %+v", action, resource, resourceUuid, err)
+		return
+	}
+
+	// audit entries contain the changed fields of all resources, only callers allowed to read the audit log receive them
+	WebsocketService().BroadcastPermitted(AUDIT_CREATED, dto.PERMISSION_AUDIT_READ, audit.ToDto())
+}
+
+// auditState captures the api representation of a resource, it must be taken before the resource is modified
+func auditState(v interface{}) dto.InterfaceMap {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var state dto.InterfaceMap
+	json.Unmarshal(b, &state)
+	return state
+}
+
+// diffAuditState lists the top level fields that differ, sorted by name
+func diffAuditState(before dto.InterfaceMap, after dto.InterfaceMap) []dto.AuditChange {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := []dto.AuditChange{}
+	for field := range fields {
+		if auditIgnoredFields[field] || reflect.DeepEqual(before[field], after[field]) {
+			continue
+		}
+		changes = append(changes, dto.AuditChange{Field: field, Before: before[field], After: after[field]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
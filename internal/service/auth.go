@@ -0,0 +1,315 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	apiKeyPrefix  = "ffm_"
+	sessionPrefix = "ffs_"
+
+	sessionDuration = 24 * time.Hour
+
+	minPasswordLength = 8
+)
+
+// bcrypt hash of a random password, only used to spend the same time on unknown usernames
+var dummyPasswordHash = "$2a$10$3euPcmQFCiblsZeEu5s7p.9OVHgeHWFDk9nhMqZ0m/3pd/lhwZgES"
+
+type authSvc struct {
+	service
+	sev              *sev.Sev
+	apiKeyRepository *repository.ApiKey
+	userRepository   *repository.User
+}
+
+// NewAuthService creates a standalone auth service, eg. for cli commands that must not start the other services
+func NewAuthService(s *sev.Sev) *authSvc {
+	return &authSvc{sev: s, apiKeyRepository: &repository.ApiKey{DB: s.DB()}, userRepository: &repository.User{DB: s.DB()}}
+}
+
+func (s *authSvc) ListApiKeys() (*[]model.ApiKey, error) {
+	return s.apiKeyRepository.List()
+}
+
+// CreateApiKey returns the new key, it is only known at this point as only its hash is stored
+func (s *authSvc) CreateApiKey(newApiKey *dto.NewApiKey) (string, *model.ApiKey, error) {
+	if newApiKey.Name == "" {
+		return "", nil, errors.New("api key requires a name")
+	}
+	if len(newApiKey.Scopes) == 0 {
+		return "", nil, errors.New("api key requires at least one scope")
+	}
+	for _, scope := range newApiKey.Scopes {
+		if !dto.IsValidScope(scope) {
+			return "", nil, fmt.Errorf("invalid scope '%s'", scope)
+		}
+	}
+
+	key, err := randomToken(apiKeyPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey, err := s.apiKeyRepository.Create(newApiKey, key[:len(apiKeyPrefix)+6], hashToken(key))
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.sev.Logger().Infof("created new api key '%s' (uuid: %s)", apiKey.Name, apiKey.Uuid)
+	return key, apiKey, nil
+}
+
+func (s *authSvc) RevokeApiKey(uuid string) error {
+	apiKey, err := s.apiKeyRepository.First(uuid)
+	if err != nil {
+		return err
+	}
+
+	if apiKey.Uuid == "" {
+		return errors.New("api key for given uuid not found")
+	}
+
+	if err := s.apiKeyRepository.Delete(apiKey); err != nil {
+		return err
+	}
+
+	s.sev.Logger().Infof("revoked api key '%s' (uuid: %s)", apiKey.Name, apiKey.Uuid)
+	return nil
+}
+
+// Login exchanges an api key or username and password for a session token, eg. for the web ui
+func (s *authSvc) Login(login *dto.Login) (*dto.Session, error) {
+	token, err := randomToken(sessionPrefix)
+	if err != nil {
+		return nil, err
+	}
+	s.apiKeyRepository.DeleteExpiredSessions()
+	expiresAt := time.Now().Add(sessionDuration).UnixMilli()
+
+	if login.Username != "" {
+		user, err := s.userRepository.FirstByUsername(login.Username)
+		if err != nil {
+			return nil, err
+		}
+		// compare against a dummy hash for unknown users to not leak their existence by timing
+		hash := user.Password
+		if hash == "" {
+			hash = dummyPasswordHash
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(login.Password)) != nil || user.Uuid == "" {
+			return nil, errors.New("invalid username or password")
+		}
+
+		if _, err := s.apiKeyRepository.CreateSession("", user.Uuid, hashToken(token), expiresAt); err != nil {
+			return nil, err
+		}
+		s.userRepository.Touch(user)
+		return &dto.Session{Token: token, Name: user.Username, Role: user.Role, ExpiresAt: expiresAt}, nil
+	}
+
+	apiKey, err := s.findApiKey(login.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.apiKeyRepository.CreateSession(apiKey.Uuid, "", hashToken(token), expiresAt); err != nil {
+		return nil, err
+	}
+	return &dto.Session{Token: token, Name: apiKey.Name, Scopes: apiKey.Scopes, ExpiresAt: expiresAt}, nil
+}
+
+func (s *authSvc) Logout(token string) error {
+	return s.apiKeyRepository.DeleteSession(hashToken(token))
+}
+
+// Authenticate resolves an api key or session token
+func (s *authSvc) Authenticate(token string) (*dto.Auth, error) {
+	if !strings.HasPrefix(token, sessionPrefix) {
+		apiKey, err := s.findApiKey(token)
+		if err != nil {
+			return nil, err
+		}
+		return s.apiKeyAuth(apiKey)
+	}
+
+	session, err := s.apiKeyRepository.FirstSessionByHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if session.Hash == "" || session.ExpiresAt < time.Now().UnixMilli() {
+		return nil, errors.New("invalid or expired session")
+	}
+
+	if session.User != "" {
+		user, err := s.userRepository.First(session.User)
+		if err != nil {
+			return nil, err
+		}
+		if user.Uuid == "" {
+			return nil, errors.New("invalid or expired session")
+		}
+		return &dto.Auth{User: user.Uuid, Name: user.Username, Role: user.Role}, nil
+	}
+
+	apiKey, err := s.apiKeyRepository.First(session.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Uuid == "" {
+		return nil, errors.New("invalid or expired session")
+	}
+	return s.apiKeyAuth(apiKey)
+}
+
+// apiKeyAuth limits keys owned by a user to the role the user currently has
+func (s *authSvc) apiKeyAuth(apiKey *model.ApiKey) (*dto.Auth, error) {
+	auth := &dto.Auth{ApiKey: apiKey.Uuid, Name: apiKey.Name, Scopes: apiKey.Scopes}
+	if apiKey.User == "" {
+		return auth, nil
+	}
+
+	user, err := s.userRepository.First(apiKey.User)
+	if err != nil {
+		return nil, err
+	}
+	if user.Uuid == "" {
+		return nil, errors.New("invalid api key")
+	}
+	auth.User = user.Uuid
+	auth.Role = user.Role
+	return auth, nil
+}
+
+func (s *authSvc) ListUsers() (*[]model.User, error) {
+	return s.userRepository.List()
+}
+
+func (s *authSvc) CreateUser(newUser *dto.NewUser) (*model.User, error) {
+	if newUser.Username == "" {
+		return nil, errors.New("user requires a username")
+	}
+	if err := validateUser(newUser, true); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.userRepository.FirstByUsername(newUser.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Uuid != "" {
+		return nil, fmt.Errorf("user '%s' already exists", newUser.Username)
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepository.Create(newUser, string(password))
+	if err != nil {
+		return nil, err
+	}
+
+	s.sev.Logger().Infof("created new user '%s' with role %s (uuid: %s)", user.Username, user.Role, user.Uuid)
+	return user, nil
+}
+
+// UpdateUser changes the role and, if given, the password of a user
+func (s *authSvc) UpdateUser(uuid string, newUser *dto.NewUser) (*model.User, error) {
+	user, err := s.userRepository.First(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if user.Uuid == "" {
+		return nil, errors.New("user for given uuid not found")
+	}
+	if err := validateUser(newUser, false); err != nil {
+		return nil, err
+	}
+
+	user.Role = newUser.Role
+	if newUser.Password != "" {
+		password, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = string(password)
+	}
+
+	if err := s.userRepository.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.sev.Logger().Infof("updated user '%s' (uuid: %s)", user.Username, user.Uuid)
+	return user, nil
+}
+
+func (s *authSvc) DeleteUser(uuid string) error {
+	user, err := s.userRepository.First(uuid)
+	if err != nil {
+		return err
+	}
+	if user.Uuid == "" {
+		return errors.New("user for given uuid not found")
+	}
+
+	if err := s.userRepository.Delete(user); err != nil {
+		return err
+	}
+
+	s.sev.Logger().Infof("deleted user '%s' (uuid: %s)", user.Username, user.Uuid)
+	return nil
+}
+
+func validateUser(newUser *dto.NewUser, requirePassword bool) error {
+	if !dto.IsValidRole(newUser.Role) {
+		return fmt.Errorf("invalid role '%s'", newUser.Role)
+	}
+	if (requirePassword || newUser.Password != "") && len(newUser.Password) < minPasswordLength {
+		return fmt.Errorf("password requires at least %d characters", minPasswordLength)
+	}
+	return nil
+}
+
+func (s *authSvc) findApiKey(key string) (*model.ApiKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, errors.New("invalid api key")
+	}
+
+	apiKey, err := s.apiKeyRepository.FirstByHash(hashToken(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Uuid == "" {
+		return nil, errors.New("invalid api key")
+	}
+
+	s.apiKeyRepository.Touch(apiKey)
+	return apiKey, nil
+}
+
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// tokens are random with 256 bits of entropy so a plain sha256 is sufficient to store them
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,564 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev"
+	"gopkg.in/yaml.v3"
+)
+
+type bundleSvc struct {
+	service
+	sev *sev.Sev
+}
+
+// Export collects all presets, watchfolders and webhooks, secrets are left out
+func (s *bundleSvc) Export() (*dto.Bundle, error) {
+	bundle := &dto.Bundle{Version: dto.BUNDLE_VERSION, ExportedAt: time.Now().UTC()}
+
+	presets, _, err := PresetService().ListPresets(-1, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range *presets {
+		preset := p.ToNewPreset()
+		preset.PreProcessing = preset.PreProcessing.WithoutSecrets()
+		preset.PostProcessing = preset.PostProcessing.WithoutSecrets()
+		bundle.Presets = append(bundle.Presets, dto.BundlePreset{Uuid: p.Uuid, NewPreset: *preset})
+	}
+
+	watchfolders, _, err := WatchfolderService().ListWatchfolders(-1, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range *watchfolders {
+		watchfolder := w.ToNewWatchfolder()
+		if watchfolder.S3 != nil {
+			s3 := *watchfolder.S3
+			s3.SecretKey = ""
+			watchfolder.S3 = &s3
+		}
+		bundle.Watchfolders = append(bundle.Watchfolders, dto.BundleWatchfolder{Uuid: w.Uuid, NewWatchfolder: *watchfolder})
+	}
+
+	webhooks, _, err := WebhookService().ListWebhooks(-1, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range *webhooks {
+		webhook := w.ToNewWebhook()
+		// header values usually carry credentials
+		webhook.Secret = ""
+		webhook.Headers = nil
+		bundle.Webhooks = append(bundle.Webhooks, dto.BundleWebhook{Uuid: w.Uuid, NewWebhook: *webhook})
+	}
+
+	s.sev.Logger().Infof("exported bundle (presets: %d, watchfolders: %d, webhooks: %d)", len(bundle.Presets), len(bundle.Watchfolders), len(bundle.Webhooks))
+	return bundle, nil
+}
+
+// Import creates the resources of a bundle. Resources conflict with existing ones of the same uuid or, failing that, the same
+// name (event and url for webhooks). References between resources (extends, the preset of a watchfolder, webhook filters) are
+// remapped to the uuids on this instance. Failing resources are reported and do not stop the import of the others.
+func (s *bundleSvc) Import(bundle *dto.Bundle, conflict dto.BundleConflict, dryRun bool, actor *dto.Actor) (*dto.BundleImport, error) {
+	switch conflict {
+	case "":
+		conflict = dto.CONFLICT_SKIP
+	case dto.CONFLICT_SKIP, dto.CONFLICT_OVERWRITE, dto.CONFLICT_RENAME:
+	default:
+		return nil, fmt.Errorf("invalid conflict handling '%s' (expected '%s', '%s' or '%s')", conflict, dto.CONFLICT_SKIP, dto.CONFLICT_OVERWRITE, dto.CONFLICT_RENAME)
+	}
+
+	i := &bundleImporter{
+		conflict: conflict,
+		dryRun:   dryRun,
+		actor:    actor,
+		uuids:    map[string]string{},
+		result:   &dto.BundleImport{DryRun: dryRun, Items: []dto.BundleImportItem{}},
+	}
+	if err := i.importPresets(bundle.Presets); err != nil {
+		return nil, err
+	}
+	if err := i.importWatchfolders(bundle.Watchfolders); err != nil {
+		return nil, err
+	}
+	if err := i.importWebhooks(bundle.Webhooks); err != nil {
+		return nil, err
+	}
+
+	s.sev.Logger().Infof("imported bundle (items: %d, dryRun: %t)", len(i.result.Items), dryRun)
+	return i.result, nil
+}
+
+// Reconcile applies the resources declared in the config directory. Declared resources are created or updated and become
+// managed, managed resources no longer declared are deleted. Presets and watchfolders declared without uuid are referenced by
+// their name.
+func (s *bundleSvc) Reconcile(bundle *dto.Bundle) (*dto.BundleImport, error) {
+	presets, watchfolders, webhooks := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for n := range bundle.Presets {
+		if err := declare(dto.AUDIT_PRESET, &bundle.Presets[n].Uuid, bundle.Presets[n].Name, presets); err != nil {
+			return nil, err
+		}
+	}
+	for n := range bundle.Watchfolders {
+		if err := declare(dto.AUDIT_WATCHFOLDER, &bundle.Watchfolders[n].Uuid, bundle.Watchfolders[n].Name, watchfolders); err != nil {
+			return nil, err
+		}
+	}
+	for n, w := range bundle.Webhooks {
+		if err := declare(dto.AUDIT_WEBHOOK, &bundle.Webhooks[n].Uuid, fmt.Sprintf("%s %s", w.Event, w.Url), webhooks); err != nil {
+			return nil, err
+		}
+	}
+
+	i := &bundleImporter{
+		conflict:  dto.CONFLICT_OVERWRITE,
+		actor:     dto.CONFIG_ACTOR,
+		uuids:     map[string]string{},
+		result:    &dto.BundleImport{Items: []dto.BundleImportItem{}},
+		reconcile: true,
+		declared:  map[string]bool{},
+	}
+	if err := i.importPresets(bundle.Presets); err != nil {
+		return nil, err
+	}
+	if err := i.importWatchfolders(bundle.Watchfolders); err != nil {
+		return nil, err
+	}
+	if err := i.importWebhooks(bundle.Webhooks); err != nil {
+		return nil, err
+	}
+	if err := i.prune(); err != nil {
+		return nil, err
+	}
+
+	s.sev.Logger().Infof("reconciled config directory (items: %d)", len(i.result.Items))
+	return i.result, nil
+}
+
+// declare keys a resource of the config directory by its uuid or, if it has none, by its name
+func declare(resource dto.AuditResource, uuid *string, name string, seen map[string]bool) error {
+	if *uuid == "" {
+		*uuid = name
+	}
+	if seen[*uuid] {
+		return fmt.Errorf("%s '%s' is declared more than once", resource, *uuid)
+	}
+	seen[*uuid] = true
+	return nil
+}
+
+// checkManaged rejects changes to resources declared in the config directory unless they are applied from there
+func checkManaged(resource dto.AuditResource, managed bool, actor *dto.Actor) error {
+	if managed && !actor.IsConfig() {
+		return fmt.Errorf("%s is managed by the config directory and read-only", resource)
+	}
+	return nil
+}
+
+// ParseBundle reads a bundle from yaml or json, json being a subset of yaml
+func ParseBundle(data []byte) (*dto.Bundle, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	// yaml is converted to json, so the json field names apply to both formats
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	bundle := &dto.Bundle{}
+	if err := json.Unmarshal(b, bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	if bundle.Version < 1 || bundle.Version > dto.BUNDLE_VERSION {
+		return nil, fmt.Errorf("unsupported bundle version %d (supported: %d)", bundle.Version, dto.BUNDLE_VERSION)
+	}
+	return bundle, nil
+}
+
+// MarshalBundle writes a bundle as yaml or json
+func MarshalBundle(bundle *dto.Bundle, format string) ([]byte, error) {
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return b, nil
+	case "yaml", "":
+		// decoding the json into a yaml node keeps the field names and their order
+		var node yaml.Node
+		if err := yaml.Unmarshal(b, &node); err != nil {
+			return nil, err
+		}
+		blockStyle(&node)
+		return yaml.Marshal(&node)
+	default:
+		return nil, fmt.Errorf("invalid format '%s' (expected 'yaml' or 'json')", format)
+	}
+}
+
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+type bundleImporter struct {
+	conflict dto.BundleConflict
+	dryRun   bool
+	actor    *dto.Actor
+	uuids    map[string]string // uuids of the bundle mapped to the uuids on this instance
+	names    []string          // names taken by resources of the type currently imported
+	result   *dto.BundleImport
+
+	reconcile bool            // unchanged resources are skipped, managed resources no longer declared are deleted
+	declared  map[string]bool // uuids on this instance of the resources of the bundle
+}
+
+func (i *bundleImporter) importPresets(presets []dto.BundlePreset) error {
+	existing, _, err := PresetService().ListPresets(-1, -1)
+	if err != nil {
+		return err
+	}
+	i.names = []string{}
+	for _, e := range *existing {
+		i.names = append(i.names, e.Name)
+	}
+
+	// base presets are imported first, so their uuid on this instance is known to the presets extending them
+	pending := presets
+	for len(pending) > 0 {
+		next := []dto.BundlePreset{}
+		for _, p := range pending {
+			if p.Extends != "" && slices.ContainsFunc(pending, func(b dto.BundlePreset) bool { return b.Uuid == p.Extends }) {
+				next = append(next, p)
+				continue
+			}
+			i.importPreset(p, *existing)
+		}
+		if len(next) == len(pending) {
+			for _, p := range next {
+				i.record(dto.BundleImportItem{Resource: dto.AUDIT_PRESET, Name: p.Name, Uuid: p.Uuid}, "", errors.New("preset inheritance cycle"))
+			}
+			break
+		}
+		pending = next
+	}
+	return nil
+}
+
+func (i *bundleImporter) importPreset(p dto.BundlePreset, existing []model.Preset) {
+	preset := p.NewPreset
+	preset.Extends = i.remap(preset.Extends)
+
+	target := ""
+	for _, e := range existing {
+		if e.Uuid == p.Uuid {
+			target = e.Uuid
+		}
+	}
+	for _, e := range existing {
+		if target == "" && e.Name == p.Name {
+			target = e.Uuid
+		}
+	}
+
+	item := dto.BundleImportItem{Resource: dto.AUDIT_PRESET, Name: preset.Name, Uuid: p.Uuid, Action: i.action(target)}
+	if item.Action == dto.BUNDLE_RENAME {
+		preset.Name = i.rename(preset.Name)
+		item.Name = preset.Name
+		target = ""
+	}
+	if i.reconcile && item.Action == dto.BUNDLE_OVERWRITE {
+		current := existing[slices.IndexFunc(existing, func(e model.Preset) bool { return e.Uuid == target })]
+		// the stored upload secret is kept if none is declared, see UpdatePreset
+		if current.PostProcessing != nil && current.PostProcessing.Upload != nil && preset.PostProcessing != nil && preset.PostProcessing.Upload != nil && preset.PostProcessing.Upload.SecretKey == "" {
+			preset.PostProcessing.Upload.SecretKey = current.PostProcessing.Upload.SecretKey
+		}
+		if current.Managed && unchanged(current.ToNewPreset(), &preset) {
+			item.Action = dto.BUNDLE_SKIP
+		}
+	}
+
+	var err error
+	switch {
+	case item.Action == dto.BUNDLE_SKIP:
+	case i.dryRun:
+		// presets extending a base are validated once their base exists
+		if preset.Extends == "" {
+			err = validatePreset(&preset)
+		}
+	case item.Action == dto.BUNDLE_OVERWRITE:
+		_, err = PresetService().UpdatePreset(target, &preset, i.actor)
+	default:
+		var created *model.Preset
+		if created, err = PresetService().NewPreset(&preset, i.actor); err == nil {
+			target = created.Uuid
+		}
+	}
+	i.record(item, target, err)
+}
+
+func (i *bundleImporter) importWatchfolders(watchfolders []dto.BundleWatchfolder) error {
+	existing, _, err := WatchfolderService().ListWatchfolders(-1, -1)
+	if err != nil {
+		return err
+	}
+	i.names = []string{}
+	for _, e := range *existing {
+		i.names = append(i.names, e.Name)
+	}
+
+	for _, w := range watchfolders {
+		watchfolder := w.NewWatchfolder
+		watchfolder.Preset = i.remap(watchfolder.Preset)
+
+		target := ""
+		for _, e := range *existing {
+			if e.Uuid == w.Uuid {
+				target = e.Uuid
+			}
+		}
+		for _, e := range *existing {
+			if target == "" && e.Name == w.Name {
+				target = e.Uuid
+			}
+		}
+
+		item := dto.BundleImportItem{Resource: dto.AUDIT_WATCHFOLDER, Name: watchfolder.Name, Uuid: w.Uuid, Action: i.action(target)}
+		if item.Action == dto.BUNDLE_RENAME {
+			watchfolder.Name = i.rename(watchfolder.Name)
+			item.Name = watchfolder.Name
+			target = ""
+		}
+		if i.reconcile && item.Action == dto.BUNDLE_OVERWRITE {
+			current := (*existing)[slices.IndexFunc(*existing, func(e model.Watchfolder) bool { return e.Uuid == target })]
+			// the stored secret is kept if none is declared, see UpdateWatchfolder
+			if current.S3 != nil && watchfolder.S3 != nil && watchfolder.S3.SecretKey == "" {
+				watchfolder.S3.SecretKey = current.S3.SecretKey
+			}
+			// validation applies the defaults stored with the watchfolder, failing declarations fail the update below
+			if validateWatchfolder(&watchfolder) == nil && current.Managed && unchanged(current.ToNewWatchfolder(), &watchfolder) {
+				item.Action = dto.BUNDLE_SKIP
+			}
+		}
+
+		var err error
+		switch {
+		case item.Action == dto.BUNDLE_SKIP:
+		case i.dryRun:
+			err = validateWatchfolder(&watchfolder)
+		case item.Action == dto.BUNDLE_OVERWRITE:
+			_, err = WatchfolderService().UpdateWatchfolder(target, &watchfolder, i.actor)
+		default:
+			var created *model.Watchfolder
+			if created, err = WatchfolderService().NewWatchfolder(&watchfolder, i.actor); err == nil {
+				target = created.Uuid
+			}
+		}
+		i.record(item, target, err)
+	}
+	return nil
+}
+
+func (i *bundleImporter) importWebhooks(webhooks []dto.BundleWebhook) error {
+	existing, _, err := WebhookService().ListWebhooks(-1, -1)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		webhook := w.NewWebhook
+		if webhook.Filter != nil {
+			filter := *webhook.Filter
+			filter.Presets = i.remapAll(filter.Presets)
+			filter.Watchfolders = i.remapAll(filter.Watchfolders)
+			webhook.Filter = &filter
+		}
+
+		// webhooks have no name, they conflict with webhooks sending the same event to the same url
+		var conflicting *model.Webhook
+		for _, e := range *existing {
+			if e.Uuid == w.Uuid {
+				conflicting = &e
+			}
+		}
+		for _, e := range *existing {
+			if conflicting == nil && e.Event == w.Event && e.Url == w.Url {
+				conflicting = &e
+			}
+		}
+		target := ""
+		if conflicting != nil {
+			target = conflicting.Uuid
+		}
+
+		item := dto.BundleImportItem{Resource: dto.AUDIT_WEBHOOK, Name: fmt.Sprintf("%s %s", webhook.Event, webhook.Url), Uuid: w.Uuid, Action: i.action(target)}
+		if item.Action == dto.BUNDLE_RENAME {
+			// an additional webhook is created
+			target = ""
+		}
+		if item.Action == dto.BUNDLE_OVERWRITE {
+			// an overwritten webhook keeps its secret and headers, as they are never exported
+			if webhook.Secret == "" {
+				webhook.Secret = conflicting.Secret
+			}
+			if webhook.Headers == nil {
+				webhook.Headers = conflicting.Headers
+			}
+			if i.reconcile && conflicting.Managed && unchanged(conflicting.ToNewWebhook(), &webhook) {
+				item.Action = dto.BUNDLE_SKIP
+			}
+		}
+		var err error
+		switch {
+		case item.Action == dto.BUNDLE_SKIP:
+		case i.dryRun:
+			err = validateWebhook(&webhook)
+		case item.Action == dto.BUNDLE_OVERWRITE:
+			_, err = WebhookService().UpdateWebhook(target, &webhook, i.actor)
+		default:
+			var created *model.Webhook
+			if created, err = WebhookService().NewWebhook(&webhook, i.actor); err == nil {
+				target = created.Uuid
+			}
+		}
+		i.record(item, target, err)
+	}
+	return nil
+}
+
+// prune deletes managed resources that are no longer declared, watchfolders and webhooks first as they reference presets
+func (i *bundleImporter) prune() error {
+	webhooks, _, err := WebhookService().ListWebhooks(-1, -1)
+	if err != nil {
+		return err
+	}
+	for _, w := range *webhooks {
+		if w.Managed && !i.declared[w.Uuid] {
+			item := dto.BundleImportItem{Resource: dto.AUDIT_WEBHOOK, Name: fmt.Sprintf("%s %s", w.Event, w.Url), Target: w.Uuid, Action: dto.BUNDLE_DELETE}
+			i.record(item, "", WebhookService().DeleteWebhook(w.Uuid, i.actor))
+		}
+	}
+
+	watchfolders, _, err := WatchfolderService().ListWatchfolders(-1, -1)
+	if err != nil {
+		return err
+	}
+	for _, w := range *watchfolders {
+		if w.Managed && !i.declared[w.Uuid] {
+			item := dto.BundleImportItem{Resource: dto.AUDIT_WATCHFOLDER, Name: w.Name, Target: w.Uuid, Action: dto.BUNDLE_DELETE}
+			i.record(item, "", WatchfolderService().DeleteWatchfolder(w.Uuid, i.actor))
+		}
+	}
+
+	presets, _, err := PresetService().ListPresets(-1, -1)
+	if err != nil {
+		return err
+	}
+	pending := []model.Preset{}
+	for _, p := range *presets {
+		if p.Managed && !i.declared[p.Uuid] {
+			pending = append(pending, p)
+		}
+	}
+	// presets extending others are deleted before their base
+	for len(pending) > 0 {
+		next := []model.Preset{}
+		for _, p := range pending {
+			if slices.ContainsFunc(pending, func(e model.Preset) bool { return e.Extends == p.Uuid }) {
+				next = append(next, p)
+				continue
+			}
+			item := dto.BundleImportItem{Resource: dto.AUDIT_PRESET, Name: p.Name, Target: p.Uuid, Action: dto.BUNDLE_DELETE}
+			i.record(item, "", PresetService().DeletePreset(p.Uuid, i.actor))
+		}
+		if len(next) == len(pending) {
+			break
+		}
+		pending = next
+	}
+	return nil
+}
+
+// unchanged compares a stored resource with its declaration
+func unchanged(current any, declared any) bool {
+	a, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(declared)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+// action decides how a resource is imported, target is the uuid of the existing resource it conflicts with
+func (i *bundleImporter) action(target string) dto.BundleAction {
+	if target == "" {
+		return dto.BUNDLE_CREATE
+	}
+	switch i.conflict {
+	case dto.CONFLICT_OVERWRITE:
+		return dto.BUNDLE_OVERWRITE
+	case dto.CONFLICT_RENAME:
+		return dto.BUNDLE_RENAME
+	default:
+		return dto.BUNDLE_SKIP
+	}
+}
+
+func (i *bundleImporter) record(item dto.BundleImportItem, target string, err error) {
+	if target != "" && i.declared != nil {
+		// failing updates keep their resource from being pruned
+		i.declared[target] = true
+	}
+	if err != nil {
+		item.Action = dto.BUNDLE_FAILED
+		item.Error = err.Error()
+	} else if target != "" {
+		item.Target = target
+		i.uuids[item.Uuid] = target
+	}
+	i.result.Items = append(i.result.Items, item)
+}
+
+// rename returns a name not taken by an existing resource
+func (i *bundleImporter) rename(name string) string {
+	renamed := name + " (imported)"
+	for n := 2; slices.Contains(i.names, renamed); n++ {
+		renamed = fmt.Sprintf("%s (imported %d)", name, n)
+	}
+	i.names = append(i.names, renamed)
+	return renamed
+}
+
+// remap returns the uuid on this instance of a resource of the bundle, other uuids are kept
+func (i *bundleImporter) remap(uuid string) string {
+	if target, ok := i.uuids[uuid]; ok {
+		return target
+	}
+	return uuid
+}
+
+func (i *bundleImporter) remapAll(uuids []string) []string {
+	if uuids == nil {
+		return nil
+	}
+	remapped := make([]string, len(uuids))
+	for n, uuid := range uuids {
+		remapped[n] = i.remap(uuid)
+	}
+	return remapped
+}
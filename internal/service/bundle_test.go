@@ -0,0 +1,231 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/metrics"
+	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBundleTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.Audit{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	s := sev.New("test", "", "", 3000)
+	s.SetDB(db)
+	Init(s)
+
+	// setup metrics
+	metrics := &metrics.Metrics{}
+	for name, gauge := range metrics.Gauges() {
+		s.Metrics().RegisterGauge(name, gauge)
+	}
+	for name, gauge := range metrics.GaugesVec() {
+		s.Metrics().RegisterGaugeVec(name, gauge)
+	}
+
+	return db, s
+}
+
+func TestBundleService(t *testing.T) {
+	db, _ := setupBundleTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	base, err := PresetService().NewPreset(&dto.NewPreset{Name: "Bundle Audio", Command: "-c:a aac"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create preset: %v", err)
+	}
+	child, err := PresetService().NewPreset(&dto.NewPreset{Name: "Bundle Video", Extends: base.Uuid, Command: "-c:v libx264 ${BASE_COMMAND}"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create preset: %v", err)
+	}
+	_, err = WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{Name: "Bundle Inbox", Path: "/bundle/watch", Preset: child.Uuid}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+	webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_CREATED, Url: "https://example.com/bundle", Secret: "s3cr3t"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	var bundle *dto.Bundle
+	t.Run("Export bundle", func(t *testing.T) {
+		exported, err := BundleService().Export()
+		if err != nil {
+			t.Fatalf("Failed to export bundle: %v", err)
+		}
+		b, err := MarshalBundle(exported, "yaml")
+		if err != nil {
+			t.Fatalf("Failed to marshal bundle: %v", err)
+		}
+		bundle, err = ParseBundle(b)
+		if err != nil {
+			t.Fatalf("Failed to parse bundle: %v", err)
+		}
+
+		if bundle.Version != dto.BUNDLE_VERSION || len(bundle.Presets) < 2 || len(bundle.Watchfolders) < 1 || len(bundle.Webhooks) < 1 {
+			t.Fatalf("Unexpected bundle %+v", bundle)
+		}
+		for _, webhook := range bundle.Webhooks {
+			if webhook.Secret != "" {
+				t.Errorf("Expected secret of webhook %s to be left out", webhook.Uuid)
+			}
+		}
+	})
+
+	t.Run("Skip existing resources", func(t *testing.T) {
+		result, err := BundleService().Import(bundle, "", false, nil)
+		if err != nil {
+			t.Fatalf("Failed to import bundle: %v", err)
+		}
+		for _, item := range result.Items {
+			if item.Action != dto.BUNDLE_SKIP {
+				t.Errorf("Expected %s to be skipped, got %s", item.Name, item.Action)
+			}
+		}
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		result, err := BundleService().Import(bundle, dto.CONFLICT_RENAME, true, nil)
+		if err != nil {
+			t.Fatalf("Failed to import bundle: %v", err)
+		}
+		if !result.DryRun || len(result.Items) != len(bundle.Presets)+len(bundle.Watchfolders)+len(bundle.Webhooks) {
+			t.Fatalf("Unexpected result %+v", result)
+		}
+		_, total, _ := PresetService().ListPresets(-1, -1)
+		if total != int64(len(bundle.Presets)) {
+			t.Errorf("Expected dry run not to create presets, got %d", total)
+		}
+	})
+
+	t.Run("Rename and remap resources", func(t *testing.T) {
+		result, err := BundleService().Import(bundle, dto.CONFLICT_RENAME, false, nil)
+		if err != nil {
+			t.Fatalf("Failed to import bundle: %v", err)
+		}
+		targets := map[string]string{}
+		for _, item := range result.Items {
+			if item.Action != dto.BUNDLE_RENAME {
+				t.Errorf("Expected %s to be renamed, got %s (%s)", item.Name, item.Action, item.Error)
+			}
+			targets[item.Uuid] = item.Target
+		}
+
+		renamed, err := PresetService().FindByUuid(targets[child.Uuid])
+		if err != nil {
+			t.Fatalf("Failed to find imported preset: %v", err)
+		}
+		if renamed.Name != "Bundle Video (imported)" || renamed.Extends != targets[base.Uuid] {
+			t.Errorf("Expected renamed preset extending the imported base, got %s extending %s", renamed.Name, renamed.Extends)
+		}
+
+		watchfolders, _, _ := WatchfolderService().ListWatchfolders(-1, -1)
+		found := false
+		for _, watchfolder := range *watchfolders {
+			if watchfolder.Name == "Bundle Inbox (imported)" {
+				found = watchfolder.Preset == renamed.Uuid
+			}
+		}
+		if !found {
+			t.Error("Expected imported watchfolder to use the imported preset")
+		}
+	})
+
+	t.Run("Reject unknown conflict handling", func(t *testing.T) {
+		if _, err := BundleService().Import(bundle, "merge", false, nil); err == nil {
+			t.Error("Expected error for unknown conflict handling")
+		}
+	})
+
+	t.Run("Overwrite webhook in place", func(t *testing.T) {
+		overwrite := &dto.Bundle{Version: dto.BUNDLE_VERSION, Webhooks: []dto.BundleWebhook{{Uuid: webhook.Uuid, NewWebhook: dto.NewWebhook{Event: dto.TASK_CREATED, Url: "https://example.com/bundle", Template: "{{ .Broken"}}}}
+		result, err := BundleService().Import(overwrite, dto.CONFLICT_OVERWRITE, false, nil)
+		if err != nil {
+			t.Fatalf("Failed to import bundle: %v", err)
+		}
+		if result.Items[0].Action != dto.BUNDLE_FAILED {
+			t.Errorf("Expected invalid template to fail, got %s", result.Items[0].Action)
+		}
+		if w, _ := WebhookService().webhookRepository.First(webhook.Uuid); w.Uuid == "" {
+			t.Fatal("Expected webhook to survive a failed overwrite")
+		}
+
+		overwrite.Webhooks[0].Template = `{"task": "{{ .data.uuid }}"}`
+		result, err = BundleService().Import(overwrite, dto.CONFLICT_OVERWRITE, false, nil)
+		if err != nil {
+			t.Fatalf("Failed to import bundle: %v", err)
+		}
+		w, _ := WebhookService().webhookRepository.First(webhook.Uuid)
+		if result.Items[0].Action != dto.BUNDLE_OVERWRITE || result.Items[0].Target != webhook.Uuid || w.Template != overwrite.Webhooks[0].Template || w.Secret != "s3cr3t" {
+			t.Errorf("Expected webhook to be updated in place keeping its secret, got %+v", result.Items[0])
+		}
+	})
+
+	t.Run("Reconcile config directory", func(t *testing.T) {
+		declared := &dto.Bundle{
+			Version:      dto.BUNDLE_VERSION,
+			Presets:      []dto.BundlePreset{{NewPreset: dto.NewPreset{Name: "Managed", Command: "-c:v libx264"}}},
+			Watchfolders: []dto.BundleWatchfolder{{NewWatchfolder: dto.NewWatchfolder{Name: "Managed Inbox", Path: "/managed", Preset: "Managed"}}},
+		}
+		result, err := BundleService().Reconcile(declared)
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		if len(result.Items) != 2 || result.Items[0].Action != dto.BUNDLE_CREATE || result.Items[1].Action != dto.BUNDLE_CREATE {
+			t.Fatalf("Unexpected result %+v", result.Items)
+		}
+		preset, _ := PresetService().FindByUuid(result.Items[0].Target)
+		watchfolder, _ := WatchfolderService().GetWatchfolderById(result.Items[1].Target)
+		if !preset.Managed || !watchfolder.Managed || watchfolder.Preset != preset.Uuid {
+			t.Errorf("Expected managed watchfolder referencing the managed preset by name, got %+v", watchfolder)
+		}
+
+		if _, err := PresetService().UpdatePreset(preset.Uuid, &dto.NewPreset{Name: "Managed", Command: "-c:v libx265"}, nil); err == nil {
+			t.Error("Expected managed preset to be read-only")
+		}
+		if err := WatchfolderService().DeleteWatchfolder(watchfolder.Uuid, nil); err == nil {
+			t.Error("Expected managed watchfolder to be read-only")
+		}
+
+		result, err = BundleService().Reconcile(declared)
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		for _, item := range result.Items {
+			if item.Action != dto.BUNDLE_SKIP {
+				t.Errorf("Expected unchanged %s to be skipped, got %s (%s)", item.Name, item.Action, item.Error)
+			}
+		}
+
+		declared.Presets[0].Command = "-c:v libx265"
+		declared.Watchfolders = nil
+		result, err = BundleService().Reconcile(declared)
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		if len(result.Items) != 2 || result.Items[0].Action != dto.BUNDLE_OVERWRITE || result.Items[1].Action != dto.BUNDLE_DELETE || result.Items[1].Target != watchfolder.Uuid {
+			t.Errorf("Expected preset to be updated and watchfolder to be deleted, got %+v", result.Items)
+		}
+
+		declared.Presets = append(declared.Presets, declared.Presets[0])
+		if _, err := BundleService().Reconcile(declared); err == nil {
+			t.Error("Expected error for duplicate declaration")
+		}
+	})
+}
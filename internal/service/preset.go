@@ -2,10 +2,19 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/library"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
 	"github.com/welovemedia/ffmate/sev"
 )
 
@@ -32,7 +41,7 @@ func (s *presetSvc) ListPresets(page int, perPage int) (*[]model.Preset, int64,
 	return s.presetRepository.List(page, perPage)
 }
 
-func (s *presetSvc) DeletePreset(uuid string) error {
+func (s *presetSvc) DeletePreset(uuid string, actor *dto.Actor) error {
 	w, err := s.presetRepository.First(uuid)
 	if err != nil {
 		return err
@@ -41,7 +50,19 @@ func (s *presetSvc) DeletePreset(uuid string) error {
 	if w.Uuid == "" {
 		return errors.New("preset for given uuid not found")
 	}
+	if err := checkManaged(dto.AUDIT_PRESET, w.Managed, actor); err != nil {
+		return err
+	}
+
+	// presets extending this one would lose their base
+	if count, err := s.presetRepository.CountExtending(w.Uuid); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("preset is extended by %d preset(s)", count)
+	}
 
+	before := auditState(w.ToDto())
+	w.UpdatedBy = actor.GetName()
 	err = s.presetRepository.Delete(w)
 	if err != nil {
 		s.sev.Logger().Warnf("failed to delete preset (uuid: %s): %+v", w.Uuid, err)
@@ -50,6 +71,8 @@ func (s *presetSvc) DeletePreset(uuid string) error {
 
 	s.sev.Logger().Infof("deleted preset (uuid: %s)", w.Uuid)
 
+	AuditService().Record(dto.AUDIT_PRESET, dto.AUDIT_DELETE, w.Uuid, before, nil, actor)
+
 	s.sev.Metrics().Gauge("preset.deleted").Inc()
 	WebhookService().Fire(dto.PRESET_DELETED, w.ToDto())
 	WebsocketService().Broadcast(PRESET_DELETED, w.ToDto())
@@ -57,9 +80,20 @@ func (s *presetSvc) DeletePreset(uuid string) error {
 	return nil
 }
 
-func (s *presetSvc) NewPreset(newPreset *dto.NewPreset) (*model.Preset, error) {
-	w, err := s.presetRepository.Create(newPreset)
+func (s *presetSvc) NewPreset(newPreset *dto.NewPreset, actor *dto.Actor) (*model.Preset, error) {
+	effective, err := s.inherit(newPreset, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePreset(effective); err != nil {
+		return nil, err
+	}
+	w, err := s.presetRepository.Create(newPreset, actor.GetName(), actor.IsConfig())
+	if err != nil {
+		return nil, err
+	}
 	s.sev.Logger().Infof("created new preset (uuid: %s)", w.Uuid)
+	AuditService().Record(dto.AUDIT_PRESET, dto.AUDIT_CREATE, w.Uuid, nil, auditState(w.ToDto()), actor)
 
 	if newPreset.GlobalPresetName != "" {
 		s.sev.Metrics().GaugeVec("preset.global").WithLabelValues(newPreset.GlobalPresetName).Inc()
@@ -69,15 +103,32 @@ func (s *presetSvc) NewPreset(newPreset *dto.NewPreset) (*model.Preset, error) {
 	WebhookService().Fire(dto.PRESET_CREATED, w.ToDto())
 	WebsocketService().Broadcast(PRESET_CREATED, w.ToDto())
 
-	return w, err
+	return w, nil
 }
 
-func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*model.Preset, error) {
+func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset, actor *dto.Actor) (*model.Preset, error) {
 	p, err := s.FindByUuid(presetUuid)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkManaged(dto.AUDIT_PRESET, p.Managed, actor); err != nil {
+		return nil, err
+	}
+	before := auditState(p.ToDto())
+	effective, err := s.inherit(newPreset, []string{p.Uuid})
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePreset(effective); err != nil {
+		return nil, err
+	}
 
+	// keep the stored upload secret if it was not sent again (secrets are never exposed via the api)
+	if p.PostProcessing != nil && p.PostProcessing.Upload != nil && newPreset.PostProcessing != nil && newPreset.PostProcessing.Upload != nil && newPreset.PostProcessing.Upload.SecretKey == "" {
+		newPreset.PostProcessing.Upload.SecretKey = p.PostProcessing.Upload.SecretKey
+	}
+
+	p.Extends = newPreset.Extends
 	p.Name = newPreset.Name
 	p.Description = newPreset.Description
 	p.Command = newPreset.Command
@@ -85,6 +136,14 @@ func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*
 	p.PostProcessing = newPreset.PostProcessing
 	p.OutputFile = newPreset.OutputFile
 	p.Priority = newPreset.Priority
+	p.Parameters = newPreset.Parameters
+	p.Managed = actor.IsConfig()
+	// presets keep their library origin when edited, so library updates are still offered
+	if newPreset.Library != "" {
+		p.Library = newPreset.Library
+		p.LibraryVersion = newPreset.LibraryVersion
+	}
+	p.UpdatedBy = actor.GetName()
 
 	err = s.presetRepository.Update(p)
 	if err != nil {
@@ -96,9 +155,325 @@ func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*
 		s.sev.Metrics().GaugeVec("preset.global").WithLabelValues(newPreset.GlobalPresetName).Inc()
 	}
 
+	AuditService().Record(dto.AUDIT_PRESET, dto.AUDIT_UPDATE, p.Uuid, before, auditState(p.ToDto()), actor)
+
 	s.sev.Metrics().Gauge("preset.updated").Inc()
 	WebhookService().Fire(dto.PRESET_UPDATED, p.ToDto())
 	WebsocketService().Broadcast(PRESET_UPDATED, p.ToDto())
 
 	return p, err
 }
+
+// EffectivePreset returns the preset with everything it inherits from its base presets applied
+func (s *presetSvc) EffectivePreset(presetUuid string) (*model.Preset, error) {
+	p, err := s.FindByUuid(presetUuid)
+	if err != nil {
+		return nil, err
+	}
+	effective, err := s.inherit(p.ToNewPreset(), []string{p.Uuid})
+	if err != nil {
+		return nil, err
+	}
+
+	e := *p
+	e.Command = effective.Command
+	e.OutputFile = effective.OutputFile
+	e.Priority = effective.Priority
+	e.PreProcessing = effective.PreProcessing
+	e.PostProcessing = effective.PostProcessing
+	e.Parameters = effective.Parameters
+	return &e, nil
+}
+
+// inherit applies the base presets of a preset, chain holds the uuids already visited to detect cycles.
+// Fields set on the preset override those of its base, parameters are merged and ${BASE_COMMAND} inserts the command of the base.
+func (s *presetSvc) inherit(preset *dto.NewPreset, chain []string) (*dto.NewPreset, error) {
+	if preset.Extends == "" {
+		return preset, nil
+	}
+	if slices.Contains(chain, preset.Extends) {
+		return nil, fmt.Errorf("preset inheritance cycle: %s -> %s", strings.Join(chain, " -> "), preset.Extends)
+	}
+	p, err := s.FindByUuid(preset.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("base preset %s not found", preset.Extends)
+	}
+	base, err := s.inherit(p.ToNewPreset(), append(chain, p.Uuid))
+	if err != nil {
+		return nil, err
+	}
+
+	effective := *preset
+	if preset.Command == "" {
+		effective.Command = base.Command
+	} else {
+		effective.Command = strings.ReplaceAll(preset.Command, "${BASE_COMMAND}", base.Command)
+	}
+	if effective.OutputFile == "" {
+		effective.OutputFile = base.OutputFile
+	}
+	if effective.Priority == 0 {
+		effective.Priority = base.Priority
+	}
+	if effective.PreProcessing == nil {
+		effective.PreProcessing = base.PreProcessing
+	}
+	if effective.PostProcessing == nil {
+		effective.PostProcessing = base.PostProcessing
+	}
+	if len(base.Parameters) > 0 {
+		effective.Parameters = maps.Clone(base.Parameters)
+		maps.Copy(effective.Parameters, preset.Parameters)
+	}
+	return &effective, nil
+}
+
+func (s *presetSvc) ListRevisions(presetUuid string, page int, perPage int) (*[]model.PresetRevision, int64, error) {
+	if _, err := s.FindByUuid(presetUuid); err != nil {
+		return nil, 0, err
+	}
+	return s.presetRepository.Revisions(presetUuid, page, perPage)
+}
+
+// FindRevision returns a revision of a preset, revision 0 returns the current one
+func (s *presetSvc) FindRevision(presetUuid string, revision uint) (*model.PresetRevision, error) {
+	p, err := s.FindByUuid(presetUuid)
+	if err != nil {
+		return nil, err
+	}
+	if revision == 0 {
+		revision = p.Revision
+	}
+	r, err := s.presetRepository.Revision(presetUuid, revision)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d of preset %s not found", revision, presetUuid)
+	}
+	return r, nil
+}
+
+func (s *presetSvc) DiffRevisions(presetUuid string, from uint, to uint) (*dto.PresetRevisionDiff, error) {
+	a, err := s.FindRevision(presetUuid, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.FindRevision(presetUuid, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PresetRevisionDiff{Preset: presetUuid, From: a.Revision, To: b.Revision, Changes: diffAuditState(auditState(a.ToDto()), auditState(b.ToDto()))}, nil
+}
+
+// RollbackPreset restores an earlier revision by storing it as a new revision, so the history is kept
+func (s *presetSvc) RollbackPreset(presetUuid string, revision uint, actor *dto.Actor) (*model.Preset, error) {
+	r, err := s.FindRevision(presetUuid, revision)
+	if err != nil {
+		return nil, err
+	}
+	s.sev.Logger().Infof("rolling back preset to revision %d (uuid: %s)", r.Revision, presetUuid)
+	return s.UpdatePreset(presetUuid, r.ToNewPreset(), actor)
+}
+
+// Library returns the presets shipped with ffmate along with the presets installed from them
+func (s *presetSvc) Library() ([]dto.LibraryPreset, error) {
+	installed, err := s.installedLibraryPresets()
+	if err != nil {
+		return nil, err
+	}
+	presets := library.Presets()
+	for n, p := range presets {
+		if i, ok := installed[p.Id]; ok {
+			presets[n].Installed = i.Uuid
+			presets[n].InstalledVersion = i.LibraryVersion
+			presets[n].UpdateAvailable = i.LibraryVersion < p.Version
+		}
+	}
+	return presets, nil
+}
+
+// InstallLibraryPreset adds a library preset to the presets or, if it is installed already, updates it to the current version
+// of the library. Changes made to an installed preset are replaced by an update, they are kept in its revisions.
+func (s *presetSvc) InstallLibraryPreset(id string, actor *dto.Actor) (*model.Preset, error) {
+	p, err := library.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := s.installedLibraryPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	preset := p.NewPreset
+	preset.GlobalPresetName = p.Id
+	preset.Library = p.Id
+	preset.LibraryVersion = p.Version
+
+	i, ok := installed[p.Id]
+	if !ok {
+		return s.NewPreset(&preset, actor)
+	}
+	if i.LibraryVersion >= p.Version {
+		return i, nil
+	}
+	s.sev.Logger().Infof("updating library preset '%s' from version %d to %d (uuid: %s)", p.Id, i.LibraryVersion, p.Version, i.Uuid)
+	return s.UpdatePreset(i.Uuid, &preset, actor)
+}
+
+// installedLibraryPresets maps the ids of library presets to the newest preset installed from them
+func (s *presetSvc) installedLibraryPresets() (map[string]*model.Preset, error) {
+	presets, _, err := s.ListPresets(-1, -1)
+	if err != nil {
+		return nil, err
+	}
+	installed := map[string]*model.Preset{}
+	for _, p := range *presets {
+		if _, ok := installed[p.Library]; p.Library != "" && !ok {
+			installed[p.Library] = &p
+		}
+	}
+	return installed, nil
+}
+
+// validatePreset rejects presets with invalid wildcards, violating the command policy or pointing outside the configured directories
+func validatePreset(preset *dto.NewPreset) error {
+	templates := append([]string{preset.Command, preset.OutputFile}, processorTemplates(preset.PreProcessing)...)
+	templates = append(templates, processorTemplates(preset.PostProcessing)...)
+	if err := validateWildcards(templates...); err != nil {
+		return err
+	}
+	if err := validateParameters(preset.Parameters, templates); err != nil {
+		return err
+	}
+	if err := ffmpeg.ValidateCommand(preset.Command); err != nil {
+		return err
+	}
+	if err := validateUpload(preset.PreProcessing, preset.PostProcessing); err != nil {
+		return err
+	}
+	if err := sandbox.ValidateOutput(preset.OutputFile); err != nil {
+		return err
+	}
+	if err := validatePrePostProcessingPaths(preset.PreProcessing); err != nil {
+		return err
+	}
+	return validatePrePostProcessingPaths(preset.PostProcessing)
+}
+
+var parameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateParameters rejects invalid parameter declarations and templates referencing undeclared parameters
+func validateParameters(parameters dto.PresetParameters, templates []string) error {
+	for _, name := range slices.Sorted(maps.Keys(parameters)) {
+		p := parameters[name]
+		if !parameterName.MatchString(name) {
+			return fmt.Errorf("invalid parameter name '%s'", name)
+		}
+		switch p.Type {
+		case dto.PARAMETER_NUMBER:
+			if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+				return fmt.Errorf("parameter '%s' has a min greater than its max", name)
+			}
+		case dto.PARAMETER_ENUM:
+			if len(p.Values) == 0 {
+				return fmt.Errorf("parameter '%s' requires values", name)
+			}
+		case dto.PARAMETER_STRING, dto.PARAMETER_BOOLEAN:
+		default:
+			return fmt.Errorf("invalid type '%s' of parameter '%s' (expected '%s', '%s', '%s' or '%s')", p.Type, name, dto.PARAMETER_NUMBER, dto.PARAMETER_ENUM, dto.PARAMETER_STRING, dto.PARAMETER_BOOLEAN)
+		}
+		if p.Default != nil {
+			if _, err := parameterValue(name, p, p.Default); err != nil {
+				return fmt.Errorf("invalid default: %v", err)
+			}
+		}
+	}
+
+	for _, raw := range templates {
+		t, err := wildcards.Parse(raw)
+		if err != nil {
+			return err
+		}
+		for _, v := range t.Variables() {
+			path := strings.Split(v, ".")
+			if path[0] != "param" || len(path) < 2 {
+				continue
+			}
+			if _, ok := parameters[path[1]]; !ok {
+				return fmt.Errorf("parameter '%s' is not declared", path[1])
+			}
+		}
+	}
+	return nil
+}
+
+// resolveParameters validates the values of a task or watchfolder against the parameters of its preset and applies the defaults
+func resolveParameters(parameters dto.PresetParameters, values *dto.InterfaceMap) (*dto.InterfaceMap, error) {
+	if values == nil {
+		values = &dto.InterfaceMap{}
+	}
+	for _, name := range slices.Sorted(maps.Keys(*values)) {
+		if _, ok := parameters[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter '%s'", name)
+		}
+	}
+	if len(parameters) == 0 {
+		return nil, nil
+	}
+
+	resolved := dto.InterfaceMap{}
+	for _, name := range slices.Sorted(maps.Keys(parameters)) {
+		p := parameters[name]
+		v := (*values)[name]
+		if v == nil {
+			if p.Default == nil {
+				return nil, fmt.Errorf("parameter '%s' is required", name)
+			}
+			v = p.Default
+		}
+		value, err := parameterValue(name, p, v)
+		if err != nil {
+			return nil, err
+		}
+		resolved[name] = value
+	}
+	return &resolved, nil
+}
+
+func parameterValue(name string, p dto.PresetParameter, v any) (any, error) {
+	switch p.Type {
+	case dto.PARAMETER_NUMBER:
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case int:
+			f = float64(n)
+		case int64:
+			f = float64(n)
+		default:
+			return nil, fmt.Errorf("parameter '%s' must be a number", name)
+		}
+		if p.Min != nil && f < *p.Min {
+			return nil, fmt.Errorf("parameter '%s' must be at least %v", name, *p.Min)
+		}
+		if p.Max != nil && f > *p.Max {
+			return nil, fmt.Errorf("parameter '%s' must be at most %v", name, *p.Max)
+		}
+		return f, nil
+	case dto.PARAMETER_ENUM:
+		if s, ok := v.(string); ok && slices.Contains(p.Values, s) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("parameter '%s' must be one of '%s'", name, strings.Join(p.Values, "', '"))
+	case dto.PARAMETER_BOOLEAN:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("parameter '%s' must be a boolean", name)
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("parameter '%s' must be a string", name)
+	}
+}
@@ -1,10 +1,13 @@
 package service
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/library"
 	"github.com/welovemedia/ffmate/sev"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,7 +19,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.Audit{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -43,7 +46,7 @@ func TestPresetService(t *testing.T) {
 			Command:     "ffmpeg -i ${INPUT_FILE} ${OUTPUT_FILE}",
 		}
 
-		preset, err := PresetService().NewPreset(newPreset)
+		preset, err := PresetService().NewPreset(newPreset, nil)
 		if err != nil {
 			t.Fatalf("Failed to create preset: %v", err)
 		}
@@ -58,6 +61,33 @@ func TestPresetService(t *testing.T) {
 		}
 	})
 
+	t.Run("Record nothing for a failed create", func(t *testing.T) {
+		// fail the revision insert, the preset insert of the same transaction is rolled back
+		db.Callback().Create().Before("gorm:create").Register("test:fail_revision", func(tx *gorm.DB) {
+			if tx.Statement.Table == "preset_revisions" {
+				tx.AddError(errors.New("revision insert failed"))
+			}
+		})
+		defer db.Callback().Create().Remove("test:fail_revision")
+
+		_, before, _ := AuditService().ListAudit(-1, -1, string(dto.AUDIT_PRESET), "")
+		if preset, err := PresetService().NewPreset(&dto.NewPreset{Name: "Failing Preset", Command: "-y -i ${INPUT_FILE} ${OUTPUT_FILE}"}, nil); err == nil || preset != nil {
+			t.Fatalf("Expected failed create to return an error, got %+v", preset)
+		}
+		_, after, _ := AuditService().ListAudit(-1, -1, string(dto.AUDIT_PRESET), "")
+		if after != before {
+			t.Errorf("Expected no audit entry for a failed create, got %d instead of %d", after, before)
+		}
+	})
+
+	t.Run("Reject upload without endpoint", func(t *testing.T) {
+		upload := &dto.NewS3Upload{S3Connection: dto.S3Connection{Bucket: "media"}, Key: "renditions/"}
+		_, err := PresetService().NewPreset(&dto.NewPreset{Name: "Upload", Command: "-y -i ${INPUT_FILE} ${OUTPUT_FILE}", PostProcessing: &dto.NewPrePostProcessing{Upload: upload}}, nil)
+		if err == nil || !strings.Contains(err.Error(), "upload requires an endpoint and a bucket") {
+			t.Errorf("Expected upload without endpoint to be rejected, got %v", err)
+		}
+	})
+
 	t.Run("List presets", func(t *testing.T) {
 		presets, total, err := PresetService().ListPresets(0, 10)
 		if err != nil {
@@ -73,18 +103,201 @@ func TestPresetService(t *testing.T) {
 		}
 	})
 
+	t.Run("Record audit trail", func(t *testing.T) {
+		actor := &dto.Actor{Name: "user:jane", Ip: "10.0.0.1"}
+		preset, err := PresetService().NewPreset(&dto.NewPreset{Name: "Audited", Command: "-c:v libx264"}, actor)
+		if err != nil {
+			t.Fatalf("Failed to create preset: %v", err)
+		}
+		_, err = PresetService().UpdatePreset(preset.Uuid, &dto.NewPreset{Name: "Audited", Command: "-c:v libx265"}, actor)
+		if err != nil {
+			t.Fatalf("Failed to update preset: %v", err)
+		}
+
+		audits, total, err := AuditService().ListAudit(0, 10, string(dto.AUDIT_PRESET), preset.Uuid)
+		if err != nil {
+			t.Fatalf("Failed to list audit: %v", err)
+		}
+		if total != 2 {
+			t.Fatalf("Expected 2 audit entries, got %d", total)
+		}
+
+		update := (*audits)[0]
+		if update.Action != dto.AUDIT_UPDATE || update.Actor != "user:jane" || update.SourceIp != "10.0.0.1" {
+			t.Errorf("Unexpected audit entry %+v", update)
+		}
+		if len(update.Changes) != 1 || update.Changes[0].Field != "command" || update.Changes[0].Before != "-c:v libx264" || update.Changes[0].After != "-c:v libx265" {
+			t.Errorf("Unexpected changes %+v", update.Changes)
+		}
+		if (*audits)[1].Action != dto.AUDIT_CREATE {
+			t.Errorf("Expected create entry, got %s", (*audits)[1].Action)
+		}
+	})
+
+	t.Run("Preset revisions", func(t *testing.T) {
+		preset, err := PresetService().NewPreset(&dto.NewPreset{Name: "Versioned", Command: "-c:v libx264"}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create preset: %v", err)
+		}
+		if preset.Revision != 1 {
+			t.Errorf("Expected revision 1, got %d", preset.Revision)
+		}
+		preset, err = PresetService().UpdatePreset(preset.Uuid, &dto.NewPreset{Name: "Versioned", Command: "-c:v libx265"}, nil)
+		if err != nil {
+			t.Fatalf("Failed to update preset: %v", err)
+		}
+		if preset.Revision != 2 {
+			t.Errorf("Expected revision 2, got %d", preset.Revision)
+		}
+
+		revisions, total, err := PresetService().ListRevisions(preset.Uuid, 0, 10)
+		if err != nil {
+			t.Fatalf("Failed to list revisions: %v", err)
+		}
+		if total != 2 || (*revisions)[0].Revision != 2 || (*revisions)[1].Command != "-c:v libx264" {
+			t.Errorf("Unexpected revisions %+v", *revisions)
+		}
+
+		diff, err := PresetService().DiffRevisions(preset.Uuid, 1, 2)
+		if err != nil {
+			t.Fatalf("Failed to diff revisions: %v", err)
+		}
+		if len(diff.Changes) != 1 || diff.Changes[0].Field != "command" || diff.Changes[0].After != "-c:v libx265" {
+			t.Errorf("Unexpected changes %+v", diff.Changes)
+		}
+
+		preset, err = PresetService().RollbackPreset(preset.Uuid, 1, nil)
+		if err != nil {
+			t.Fatalf("Failed to roll back preset: %v", err)
+		}
+		if preset.Revision != 3 || preset.Command != "-c:v libx264" {
+			t.Errorf("Expected revision 3 with the command of revision 1, got %d (%s)", preset.Revision, preset.Command)
+		}
+
+		if _, err := PresetService().FindRevision(preset.Uuid, 4); err == nil {
+			t.Error("Expected error when finding missing revision")
+		}
+	})
+
+	t.Run("Preset inheritance", func(t *testing.T) {
+		base, err := PresetService().NewPreset(&dto.NewPreset{
+			Name:           "Audio",
+			Command:        "-c:a aac -b:a ${param.bitrate}",
+			Priority:       5,
+			PostProcessing: &dto.NewPrePostProcessing{ScriptPath: "notify.sh"},
+			Parameters:     dto.PresetParameters{"bitrate": {Type: dto.PARAMETER_STRING, Default: "192k"}},
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create base preset: %v", err)
+		}
+		child, err := PresetService().NewPreset(&dto.NewPreset{
+			Name:       "H264",
+			Extends:    base.Uuid,
+			Command:    "-i ${INPUT_FILE} -c:v libx264 ${BASE_COMMAND} ${OUTPUT_FILE}",
+			OutputFile: "/out/${INPUT_FILE_BASENAME}.mp4",
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create preset: %v", err)
+		}
+
+		effective, err := PresetService().EffectivePreset(child.Uuid)
+		if err != nil {
+			t.Fatalf("Failed to get effective preset: %v", err)
+		}
+		if effective.Command != "-i ${INPUT_FILE} -c:v libx264 -c:a aac -b:a ${param.bitrate} ${OUTPUT_FILE}" {
+			t.Errorf("Unexpected command %s", effective.Command)
+		}
+		if effective.Priority != 5 || effective.PostProcessing == nil || effective.PostProcessing.ScriptPath != "notify.sh" || effective.Parameters["bitrate"].Default != "192k" {
+			t.Errorf("Expected fields of the base preset, got %+v", effective)
+		}
+		if effective.OutputFile != "/out/${INPUT_FILE_BASENAME}.mp4" {
+			t.Errorf("Expected output file of the preset, got %s", effective.OutputFile)
+		}
+
+		_, err = PresetService().UpdatePreset(base.Uuid, &dto.NewPreset{Name: "Audio", Extends: child.Uuid, Command: "-c:a aac"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "preset inheritance cycle") {
+			t.Errorf("Expected cycle to be rejected, got %v", err)
+		}
+		if err := PresetService().DeletePreset(base.Uuid, nil); err == nil {
+			t.Error("Expected error when deleting an extended preset")
+		}
+	})
+
+	t.Run("Preset library", func(t *testing.T) {
+		// every library preset must pass the validation of presets
+		for _, p := range library.Presets() {
+			if err := validatePreset(&p.NewPreset); err != nil {
+				t.Errorf("Library preset %s is invalid: %v", p.Id, err)
+			}
+			preset, err := PresetService().InstallLibraryPreset(p.Id, nil)
+			if err != nil {
+				t.Fatalf("Failed to install library preset %s: %v", p.Id, err)
+			}
+			if preset.Library != p.Id || preset.LibraryVersion != p.Version {
+				t.Errorf("Expected preset installed from %s at version %d, got %s at %d", p.Id, p.Version, preset.Library, preset.LibraryVersion)
+			}
+		}
+
+		installed, err := PresetService().InstallLibraryPreset("h264-web", nil)
+		if err != nil {
+			t.Fatalf("Failed to install library preset: %v", err)
+		}
+		if installed.Revision != 1 {
+			t.Errorf("Expected installing an up to date preset to keep it, got revision %d", installed.Revision)
+		}
+
+		db.Model(&model.Preset{}).Where("uuid = ?", installed.Uuid).Update("library_version", 0)
+		presets, err := PresetService().Library()
+		if err != nil {
+			t.Fatalf("Failed to list library: %v", err)
+		}
+		for _, p := range presets {
+			if p.Installed == "" || p.UpdateAvailable != (p.Id == "h264-web") {
+				t.Errorf("Unexpected state of library preset %s: installed %s, update available %t", p.Id, p.Installed, p.UpdateAvailable)
+			}
+		}
+
+		updated, err := PresetService().InstallLibraryPreset("h264-web", nil)
+		if err != nil {
+			t.Fatalf("Failed to update library preset: %v", err)
+		}
+		if updated.Uuid != installed.Uuid || updated.Revision != 2 || updated.LibraryVersion == 0 {
+			t.Errorf("Expected installed preset to be updated, got %s at revision %d", updated.Uuid, updated.Revision)
+		}
+
+		if _, err := PresetService().InstallLibraryPreset("missing", nil); err == nil {
+			t.Error("Expected error when installing missing library preset")
+		}
+	})
+
+	t.Run("Reject invalid parameters", func(t *testing.T) {
+		min, max := 10.0, 1.0
+		invalid := map[string]*dto.NewPreset{
+			"invalid type 'list'":             {Command: "-c:v libx264", Parameters: dto.PresetParameters{"crf": {Type: "list"}}},
+			"min greater than its max":        {Command: "-c:v libx264", Parameters: dto.PresetParameters{"crf": {Type: dto.PARAMETER_NUMBER, Min: &min, Max: &max}}},
+			"'speed' requires values":         {Command: "-c:v libx264", Parameters: dto.PresetParameters{"speed": {Type: dto.PARAMETER_ENUM}}},
+			"must be a boolean":               {Command: "-c:v libx264", Parameters: dto.PresetParameters{"hdr": {Type: dto.PARAMETER_BOOLEAN, Default: "yes"}}},
+			"parameter 'crf' is not declared": {Command: "-crf ${param.crf}"},
+		}
+		for want, preset := range invalid {
+			if _, err := PresetService().NewPreset(preset, nil); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error containing '%s', got %v", want, err)
+			}
+		}
+	})
+
 	t.Run("Delete preset", func(t *testing.T) {
 		newPreset := &dto.NewPreset{
 			Name:    "To Delete",
 			Command: "test",
 		}
 
-		preset, err := PresetService().NewPreset(newPreset)
+		preset, err := PresetService().NewPreset(newPreset, nil)
 		if err != nil {
 			t.Fatalf("Failed to create preset: %v", err)
 		}
 
-		err = PresetService().DeletePreset(preset.Uuid)
+		err = PresetService().DeletePreset(preset.Uuid, nil)
 		if err != nil {
 			t.Fatalf("Failed to delete preset: %v", err)
 		}
@@ -6,6 +6,9 @@ import (
 )
 
 type service struct {
+	audit       *auditSvc
+	auth        *authSvc
+	bundle      *bundleSvc
 	preset      *presetSvc
 	task        *taskSvc
 	watchfolder *watchfolderSvc
@@ -16,16 +19,41 @@ type service struct {
 var services *service
 
 func Init(s *sev.Sev) {
+	// stop the dispatcher of a previous initialization so deliveries are never sent twice
+	if services != nil {
+		services.webhook.dispatcher.stop()
+	}
+
+	webhookRepository := &repository.Webhook{DB: s.DB()}
+	webhookDeliveryRepository := &repository.WebhookDelivery{DB: s.DB()}
+
 	services = &service{
+		audit:       &auditSvc{sev: s, auditRepository: &repository.Audit{DB: s.DB()}},
+		auth:        NewAuthService(s),
+		bundle:      &bundleSvc{sev: s},
 		preset:      &presetSvc{sev: s, presetRepository: &repository.Preset{DB: s.DB()}},
 		task:        &taskSvc{sev: s, taskRepository: &repository.Task{DB: s.DB()}},
 		watchfolder: &watchfolderSvc{sev: s, watchfolderRepository: &repository.Watchfolder{DB: s.DB()}},
-		webhook:     &webhookSvc{sev: s, webhookRepository: &repository.Webhook{DB: s.DB()}},
+		webhook:     &webhookSvc{sev: s, webhookRepository: webhookRepository, webhookDeliveryRepository: webhookDeliveryRepository, dispatcher: newWebhookDispatcher(s, webhookRepository, webhookDeliveryRepository)},
 		websocket:   &websocketSvc{},
 	}
+
+	services.webhook.dispatcher.start()
 }
 
 // Accessor methods
+func AuditService() *auditSvc {
+	return services.audit
+}
+
+func AuthService() *authSvc {
+	return services.auth
+}
+
+func BundleService() *bundleSvc {
+	return services.bundle
+}
+
 func PresetService() *presetSvc {
 	return services.preset
 }
@@ -1,13 +1,20 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mattn/go-shellwords"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
 	"github.com/welovemedia/ffmate/sev"
 )
 
@@ -42,6 +49,10 @@ func (s *taskSvc) GetTasksByBatchId(uuid string, page int, perPage int) (*[]mode
 	return s.taskRepository.ByBatchId(uuid, page, perPage)
 }
 
+func (s *taskSvc) GetFinishedTasksByWatchfolder(uuid string, since int64) (*[]model.Task, error) {
+	return s.taskRepository.FinishedByWatchfolder(uuid, since)
+}
+
 func (s *taskSvc) UpdateTask(task *model.Task) (*model.Task, error) {
 	task, err := s.taskRepository.UpdateTask(task)
 	WebsocketService().Broadcast(TASK_UPDATED, task.ToDto())
@@ -63,7 +74,33 @@ func (s *taskSvc) UpdateTask(task *model.Task) (*model.Task, error) {
 	return task, err
 }
 
-func (s *taskSvc) DeleteTask(uuid string) error {
+// websocket subjects of the task lifecycle events
+var lifecycleSubjects = map[dto.WebhookEvent]Subject{
+	dto.TASK_STARTED:           TASK_STARTED,
+	dto.TASK_SUCCEEDED:         TASK_SUCCEEDED,
+	dto.TASK_FAILED:            TASK_FAILED,
+	dto.TASK_CANCELED:          TASK_CANCELED,
+	dto.TASK_RETRYING:          TASK_RETRYING,
+	dto.PRE_PROCESSING_FAILED:  PRE_PROCESSING_FAILED,
+	dto.POST_PROCESSING_FAILED: POST_PROCESSING_FAILED,
+}
+
+// FireLifecycle announces a lifecycle transition of a task in addition to the generic task.updated event
+func (s *taskSvc) FireLifecycle(event dto.WebhookEvent, task *model.Task) {
+	WebhookService().Fire(event, task.ToDto())
+	WebsocketService().Broadcast(lifecycleSubjects[event], task.ToDto())
+}
+
+// UpdateTaskProgress stores a progress update, webhooks may skip these updates
+func (s *taskSvc) UpdateTaskProgress(task *model.Task) (*model.Task, error) {
+	task, err := s.taskRepository.UpdateTask(task)
+	WebsocketService().Broadcast(TASK_UPDATED, task.ToDto())
+	s.sev.Metrics().Gauge("task.updated").Inc()
+	WebhookService().FireProgress(dto.TASK_UPDATED, task.ToDto())
+	return task, err
+}
+
+func (s *taskSvc) DeleteTask(uuid string, actor *dto.Actor) error {
 	w, err := s.taskRepository.First(uuid)
 	if err != nil {
 		return err
@@ -77,6 +114,7 @@ func (s *taskSvc) DeleteTask(uuid string) error {
 		return errors.New("running tasks can not be deleted, cancel first")
 	}
 
+	w.UpdatedBy = actor.GetName()
 	err = s.taskRepository.Delete(w)
 	if err != nil {
 		s.sev.Logger().Warnf("failed to delete task (uuid: %s): %+v", w.Uuid, err)
@@ -92,7 +130,7 @@ func (s *taskSvc) DeleteTask(uuid string) error {
 	return nil
 }
 
-func (s *taskSvc) RestartTask(uuid string) (*model.Task, error) {
+func (s *taskSvc) RestartTask(uuid string, actor *dto.Actor) (*model.Task, error) {
 	t, err := s.GetTaskByUuid(uuid)
 	if err != nil {
 		return nil, err
@@ -107,11 +145,16 @@ func (s *taskSvc) RestartTask(uuid string) (*model.Task, error) {
 	t.FinishedAt = 0
 	t.Error = ""
 	t.Status = dto.QUEUED
+	t.UpdatedBy = actor.GetName()
 	s.sev.Metrics().Gauge("task.restarted").Inc()
-	return s.UpdateTask(t)
+	t, err = s.UpdateTask(t)
+	if err == nil {
+		s.FireLifecycle(dto.TASK_RETRYING, t)
+	}
+	return t, err
 }
 
-func (s *taskSvc) CancelTask(uuid string) (*model.Task, error) {
+func (s *taskSvc) CancelTask(uuid string, actor *dto.Actor) (*model.Task, error) {
 	t, err := s.GetTaskByUuid(uuid)
 	if err != nil {
 		return nil, err
@@ -121,7 +164,9 @@ func (s *taskSvc) CancelTask(uuid string) (*model.Task, error) {
 		return nil, errors.New("failed to cancel task, task in unsupported state")
 	}
 
-	if t.Status == dto.RUNNING {
+	// running tasks announce their cancellation once the queue stopped them
+	running := t.Status == dto.RUNNING
+	if running {
 		taskUpdates <- t
 	}
 
@@ -129,16 +174,187 @@ func (s *taskSvc) CancelTask(uuid string) (*model.Task, error) {
 	t.Remaining = -1
 	t.FinishedAt = time.Now().UnixMilli()
 	t.Status = dto.DONE_CANCELED
+	t.UpdatedBy = actor.GetName()
 	s.sev.Metrics().Gauge("task.canceled").Inc()
-	return s.UpdateTask(t)
+	t, err = s.UpdateTask(t)
+	if err == nil && !running {
+		s.FireLifecycle(dto.TASK_CANCELED, t)
+	}
+	return t, err
 }
 
 func (s *taskSvc) NewTask(task *dto.NewTask, batch string, source string) (*model.Task, error) {
+	if err := s.prepareTask(task); err != nil {
+		return nil, err
+	}
+	t, err := s.taskRepository.Create(task, batch, source, s.sev.Session())
+	if err != nil {
+		return nil, err
+	}
+
+	s.sev.Metrics().Gauge("task.created").Inc()
+	WebhookService().Fire(dto.TASK_CREATED, t.ToDto())
+	WebsocketService().Broadcast(TASK_CREATED, t.ToDto())
+
+	s.sev.Logger().Infof("new task added to queue (uuid: %s)", t.Uuid)
+	return t, err
+}
+
+// DryRun resolves a task like the queue would right before processing and runs all checks, the task is never created
+func (s *taskSvc) DryRun(ctx context.Context, newTask *dto.NewTask, source string) (dryRun *dto.DryRunTask, err error) {
+	// a panicking template function fails the dry run instead of the request handler
+	defer func() {
+		if r := recover(); r != nil {
+			dryRun, err = nil, fmt.Errorf("failed to resolve task: %v", r)
+		}
+	}()
+	if err := s.prepareTask(newTask); err != nil {
+		return nil, err
+	}
+	task := s.taskRepository.New(newTask, "", source, s.sev.Session())
+	wc := s.WildcardContext(task, ctx)
+
+	// preProcessing resolves against the raw input and output file
+	if err := resolvePrePostProcessing(task.PreProcessing, wc, "preProcessing"); err != nil {
+		return nil, err
+	}
+
+	inFile, err := wc.Render(task.InputFile.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve input file: %v", err)
+	}
+	outFile, err := wc.Render(task.OutputFile.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output file: %v", err)
+	}
+	task.InputFile.Resolved = inFile
+	task.OutputFile.Resolved = outFile
+
+	// the files are checked before the command is rendered, as ${probe.*} runs ffprobe on the input file
+	if err := ffmpeg.CheckInput(inFile); err != nil {
+		return nil, err
+	}
+	if err := ffmpeg.CheckOutput(outFile); err != nil {
+		return nil, err
+	}
+	wc.InputFile = inFile
+	wc.OutputFile = outFile
+	command, err := wc.Render(task.Command.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve command: %v", err)
+	}
+	task.Command.Resolved = command
+
+	args, err := ffmpeg.SplitCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse command: %v", err)
+	}
+	if err := ffmpeg.CheckArguments(args); err != nil {
+		return nil, err
+	}
+
+	if err := resolvePrePostProcessing(task.PostProcessing, wc, "postProcessing"); err != nil {
+		return nil, err
+	}
+
+	return &dto.DryRunTask{Task: *task.ToDto(), Arguments: args}, nil
+}
+
+// WildcardContext creates the context all wildcards of a task resolve from, starting with the raw input and output file
+func (s *taskSvc) WildcardContext(task *model.Task, ctx context.Context) *wildcards.Context {
+	wc := &wildcards.Context{
+		InputFile:  task.InputFile.Raw,
+		OutputFile: task.OutputFile.Raw,
+		Source:     task.Source,
+		TaskUuid:   task.Uuid,
+		BatchUuid:  task.Batch,
+	}
+	// dates and timestamps resolve to the start of the task
+	if task.StartedAt != 0 {
+		wc.Now = time.UnixMilli(task.StartedAt)
+	}
+	if task.Metadata != nil {
+		wc.Metadata = *task.Metadata
+	}
+	if task.Params != nil {
+		wc.Params = *task.Params
+	}
+	wc.Probe = func() (map[string]any, error) {
+		// the input file may not be resolved yet, so it is checked on every probe
+		if err := ffmpeg.CheckInput(wc.InputFile); err != nil {
+			return nil, err
+		}
+		return ffmpeg.Probe(ctx, wc.InputFile)
+	}
+	return wc
+}
+
+// resolvePrePostProcessing resolves the sidecar, script and upload of a dry run and checks them against the sandbox
+func resolvePrePostProcessing(processor *dto.PrePostProcessing, wc *wildcards.Context, processorType string) error {
+	if processor == nil {
+		return nil
+	}
+	if processor.SidecarPath != nil && processor.SidecarPath.Raw != "" {
+		resolved, err := wc.Render(processor.SidecarPath.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s sidecar: %v", processorType, err)
+		}
+		processor.SidecarPath.Resolved = resolved
+		if err := sandbox.CheckSidecar(resolved); err != nil {
+			return err
+		}
+	}
+	if processor.ScriptPath != nil && processor.ScriptPath.Raw != "" {
+		resolved, err := wc.Render(processor.ScriptPath.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s script: %v", processorType, err)
+		}
+		processor.ScriptPath.Resolved = resolved
+		if _, err := shellwords.NewParser().Parse(resolved); err != nil {
+			return fmt.Errorf("failed to parse %s script: %v", processorType, err)
+		}
+		if err := sandbox.CheckScript(resolved); err != nil {
+			return err
+		}
+	}
+	if upload := processor.Upload; upload != nil {
+		if upload.Files != nil && upload.Files.Raw != "" {
+			resolved, err := wc.Render(upload.Files.Raw)
+			if err != nil {
+				return fmt.Errorf("failed to resolve upload files: %v", err)
+			}
+			upload.Files.Resolved = strings.Trim(resolved, "\"")
+			if err := sandbox.CheckUpload(upload.Files.Resolved); err != nil {
+				return err
+			}
+		}
+		resolved, err := wc.Render(upload.Key.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to resolve upload key: %v", err)
+		}
+		upload.Key.Resolved = strings.Trim(resolved, "\"")
+	}
+	return nil
+}
+
+// prepareTask applies the preset of a task and validates it
+func (s *taskSvc) prepareTask(task *dto.NewTask) error {
 	if task.Preset != "" {
-		preset, err := PresetService().FindByUuid(task.Preset)
+		revision, err := PresetService().FindRevision(task.Preset, task.PresetRevision)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		task.PresetRevision = revision.Revision
+		// base presets are applied at their current revision
+		preset, err := PresetService().inherit(revision.ToNewPreset(), []string{revision.Preset})
+		if err != nil {
+			return err
 		}
+		params, err := resolveParameters(preset.Parameters, task.Params)
+		if err != nil {
+			return err
+		}
+		task.Params = params
 		task.Command = preset.Command
 		if task.OutputFile == "" {
 			task.OutputFile = preset.OutputFile
@@ -150,20 +366,22 @@ func (s *taskSvc) NewTask(task *dto.NewTask, batch string, source string) (*mode
 			task.PreProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PreProcessing.ScriptPath, SidecarPath: preset.PreProcessing.SidecarPath}
 		}
 		if preset.PostProcessing != nil && task.PostProcessing == nil {
-			task.PostProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PostProcessing.ScriptPath, SidecarPath: preset.PostProcessing.SidecarPath}
+			task.PostProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PostProcessing.ScriptPath, SidecarPath: preset.PostProcessing.SidecarPath, Upload: preset.PostProcessing.Upload}
 		}
+	} else if task.Params != nil && len(*task.Params) > 0 {
+		return errors.New("parameters require a preset")
 	}
-	t, err := s.taskRepository.Create(task, batch, source, s.sev.Session())
-	if err != nil {
-		return nil, err
+	if err := validateUpload(task.PreProcessing, task.PostProcessing); err != nil {
+		return err
 	}
-
-	s.sev.Metrics().Gauge("task.created").Inc()
-	WebhookService().Fire(dto.TASK_CREATED, t.ToDto())
-	WebsocketService().Broadcast(TASK_CREATED, t.ToDto())
-
-	s.sev.Logger().Infof("new task added to queue (uuid: %s)", t.Uuid)
-	return t, err
+	templates := append([]string{task.Command, task.InputFile, task.OutputFile}, processorTemplates(task.PreProcessing)...)
+	if err := validateWildcards(append(templates, processorTemplates(task.PostProcessing)...)...); err != nil {
+		return err
+	}
+	if err := validateTaskPaths(task); err != nil {
+		return err
+	}
+	return ffmpeg.ValidateCommand(task.Command)
 }
 
 func (s *taskSvc) NewTasks(tasks *[]dto.NewTask) (*[]model.Task, error) {
@@ -188,3 +406,65 @@ func (s *taskSvc) NewTasks(tasks *[]dto.NewTask) (*[]model.Task, error) {
 	WebhookService().Fire(dto.BATCH_CREATED, taskDTOs)
 	return &newTasks, nil
 }
+
+// validateTaskPaths rejects tasks pointing outside the configured input, output and script directories
+func validateTaskPaths(task *dto.NewTask) error {
+	if err := sandbox.ValidateInput(task.InputFile); err != nil {
+		return err
+	}
+	if err := sandbox.ValidateOutput(task.OutputFile); err != nil {
+		return err
+	}
+	if err := validatePrePostProcessingPaths(task.PreProcessing); err != nil {
+		return err
+	}
+	return validatePrePostProcessingPaths(task.PostProcessing)
+}
+
+// validateUpload rejects uploads during preProcessing and uploads without a target, they would only fail after ffmpeg finished
+func validateUpload(preProcessing *dto.NewPrePostProcessing, postProcessing *dto.NewPrePostProcessing) error {
+	if preProcessing != nil && preProcessing.Upload != nil {
+		return errors.New("upload is only supported as postProcessing action")
+	}
+	if postProcessing != nil && postProcessing.Upload != nil && (postProcessing.Upload.Endpoint == "" || postProcessing.Upload.Bucket == "") {
+		return errors.New("upload requires an endpoint and a bucket")
+	}
+	return nil
+}
+
+func validatePrePostProcessingPaths(processor *dto.NewPrePostProcessing) error {
+	if processor == nil {
+		return nil
+	}
+	if err := sandbox.ValidateSidecar(processor.SidecarPath); err != nil {
+		return err
+	}
+	if processor.Upload != nil {
+		if err := sandbox.ValidateUpload(processor.Upload.Files); err != nil {
+			return err
+		}
+	}
+	return sandbox.ValidateScript(processor.ScriptPath)
+}
+
+// validateWildcards parses every template, so unknown variables and syntax errors are reported on submission
+func validateWildcards(templates ...string) error {
+	for _, t := range templates {
+		if _, err := wildcards.Parse(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processorTemplates returns the fields of a pre- or postProcessing supporting wildcards
+func processorTemplates(processor *dto.NewPrePostProcessing) []string {
+	if processor == nil {
+		return nil
+	}
+	templates := []string{processor.ScriptPath, processor.SidecarPath}
+	if processor.Upload != nil {
+		templates = append(templates, processor.Upload.Key, processor.Upload.Files)
+	}
+	return templates
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/sev"
@@ -16,7 +23,7 @@ func setupTaskTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Task{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Task{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -86,7 +93,7 @@ func TestTaskService(t *testing.T) {
 			t.Fatalf("Failed to create task: %v", err)
 		}
 
-		cancelled, err := TaskService().CancelTask(task.Uuid)
+		cancelled, err := TaskService().CancelTask(task.Uuid, nil)
 		if err != nil {
 			t.Fatalf("Failed to cancel task: %v", err)
 		}
@@ -96,6 +103,68 @@ func TestTaskService(t *testing.T) {
 		}
 	})
 
+	t.Run("Fire lifecycle events", func(t *testing.T) {
+		events := make(chan string, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			events <- payload["event"].(string)
+		}))
+		defer server.Close()
+
+		WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_CANCELED, Url: server.URL}, nil)
+		WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_RETRYING, Url: server.URL}, nil)
+
+		task, _ := TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/test/output.mp4", Command: "test"}, "", "test")
+		TaskService().CancelTask(task.Uuid, nil)
+		TaskService().RestartTask(task.Uuid, nil)
+
+		for _, expected := range []dto.WebhookEvent{dto.TASK_CANCELED, dto.TASK_RETRYING} {
+			select {
+			case event := <-events:
+				if event != string(expected) {
+					t.Errorf("Expected event %s, got %s", expected, event)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("Timeout waiting for event %s", expected)
+			}
+		}
+	})
+
+	t.Run("Reject unknown wildcard", func(t *testing.T) {
+		_, err := TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/test/${INPUT_FILE_NAME}.mp4", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}"}, "", "test")
+		if err == nil || !strings.Contains(err.Error(), "unknown variable 'INPUT_FILE_NAME'") {
+			t.Errorf("Expected unknown wildcard to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Reject upload without endpoint", func(t *testing.T) {
+		upload := &dto.NewS3Upload{S3Connection: dto.S3Connection{Bucket: "media"}, Key: "renditions/"}
+		_, err := TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/test/output.mp4", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", PostProcessing: &dto.NewPrePostProcessing{Upload: upload}}, "", "test")
+		if err == nil || !strings.Contains(err.Error(), "upload requires an endpoint and a bucket") {
+			t.Errorf("Expected upload without endpoint to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Reject paths outside sandbox", func(t *testing.T) {
+		viper.Set("outputRoots", []string{"/data/out"})
+		config.Init()
+		defer func() {
+			viper.Set("outputRoots", []string{})
+			config.Init()
+		}()
+
+		_, err := TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/etc/cron.d/job", Command: "test"}, "", "test")
+		if err == nil {
+			t.Error("Expected task writing outside the output roots to be rejected")
+		}
+
+		_, err = TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/data/out/${INPUT_FILE_BASENAME}.mp4", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}"}, "", "test")
+		if err != nil {
+			t.Errorf("Expected task inside the output roots to be accepted, got %v", err)
+		}
+	})
+
 	t.Run("Delete task", func(t *testing.T) {
 		newTask := &dto.NewTask{
 			InputFile:  "/test/input.mp4",
@@ -108,7 +177,7 @@ func TestTaskService(t *testing.T) {
 			t.Fatalf("Failed to create task: %v", err)
 		}
 
-		err = TaskService().DeleteTask(task.Uuid)
+		err = TaskService().DeleteTask(task.Uuid, nil)
 		if err != nil {
 			t.Fatalf("Failed to delete task: %v", err)
 		}
@@ -2,10 +2,14 @@ package service
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/sandbox"
+	"github.com/welovemedia/ffmate/internal/utils/schedule"
 	"github.com/welovemedia/ffmate/sev"
 )
 
@@ -38,7 +42,16 @@ func (s *watchfolderSvc) UpdateWatchfolderInternal(watchfolder *model.Watchfolde
 	return w, err
 }
 
-func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
+// ReportError notifies webhooks and websocket clients about a watchfolder whose health degraded
+func (s *watchfolderSvc) ReportError(watchfolder *model.Watchfolder) {
+	s.sev.Logger().Warnf("watchfolder health changed to %s after %d consecutive failures (uuid: %s): %s", watchfolder.Health, watchfolder.ConsecutiveFailures, watchfolder.Uuid, watchfolder.Error)
+
+	s.sev.Metrics().Gauge("watchfolder.error").Inc()
+	WebhookService().Fire(dto.WATCHFOLDER_ERROR, watchfolder.ToDto())
+	WebsocketService().Broadcast(WATCHFOLDER_ERROR, watchfolder.ToDto())
+}
+
+func (s *watchfolderSvc) DeleteWatchfolder(uuid string, actor *dto.Actor) error {
 	w, err := s.watchfolderRepository.First(uuid)
 	if err != nil {
 		return err
@@ -47,6 +60,9 @@ func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 	if w.Uuid == "" {
 		return errors.New("watchfolder for given uuid not found")
 	}
+	if err := checkManaged(dto.AUDIT_WATCHFOLDER, w.Managed, actor); err != nil {
+		return err
+	}
 
 	err = s.watchfolderRepository.Delete(w)
 	if err != nil {
@@ -55,8 +71,13 @@ func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 	}
 
 	s.sev.Logger().Infof("deleted watchfolder (uuid: %s)", w.Uuid)
+	AuditService().Record(dto.AUDIT_WATCHFOLDER, dto.AUDIT_DELETE, w.Uuid, auditState(w.ToDto()), nil, actor)
 	watchfolderUpdates <- w
 
+	for _, name := range []string{"watchfolder.health", "watchfolder.consecutiveFailures", "watchfolder.lastSuccessfulScan", "watchfolder.filesSeen", "watchfolder.filesIngested"} {
+		s.sev.Metrics().GaugeVec(name).DeleteLabelValues(w.Uuid)
+	}
+
 	s.sev.Metrics().Gauge("watchfolder.deleted").Inc()
 	WebhookService().Fire(dto.WATCHFOLDER_DELETED, w.ToDto())
 	WebsocketService().Broadcast(WATCHFOLDER_DELETED, w.ToDto())
@@ -64,41 +85,119 @@ func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 	return nil
 }
 
-func (s *watchfolderSvc) NewWatchfolder(newWatchfolder *dto.NewWatchfolder) (*model.Watchfolder, error) {
-	_, err := PresetService().FindByUuid(newWatchfolder.Preset)
+func (s *watchfolderSvc) NewWatchfolder(newWatchfolder *dto.NewWatchfolder, actor *dto.Actor) (*model.Watchfolder, error) {
+	preset, err := PresetService().EffectivePreset(newWatchfolder.Preset)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := resolveParameters(preset.Parameters, newWatchfolder.Params); err != nil {
+		return nil, err
+	}
+	if err := validateWatchfolder(newWatchfolder); err != nil {
+		return nil, err
+	}
+	w, err := s.watchfolderRepository.Create(newWatchfolder, actor.IsConfig())
 	if err != nil {
 		return nil, err
 	}
-	w, err := s.watchfolderRepository.Create(newWatchfolder)
 
 	s.sev.Logger().Infof("created new watchfolder (uuid: %s)", w.Uuid)
+	AuditService().Record(dto.AUDIT_WATCHFOLDER, dto.AUDIT_CREATE, w.Uuid, nil, auditState(w.ToDto()), actor)
 	watchfolderUpdates <- w
 
 	s.sev.Metrics().Gauge("watchfolder.created").Inc()
 	WebhookService().Fire(dto.WATCHFOLDER_CREATED, w.ToDto())
 	WebsocketService().Broadcast(WATCHFOLDER_CREATED, w.ToDto())
 
-	return w, err
+	return w, nil
 }
 
-func (s *watchfolderSvc) UpdateWatchfolder(watchfolderUuid string, newWatchfolder *dto.NewWatchfolder) (*model.Watchfolder, error) {
+func (s *watchfolderSvc) UpdateWatchfolder(watchfolderUuid string, newWatchfolder *dto.NewWatchfolder, actor *dto.Actor) (*model.Watchfolder, error) {
 	w, err := s.GetWatchfolderById(watchfolderUuid)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkManaged(dto.AUDIT_WATCHFOLDER, w.Managed, actor); err != nil {
+		return nil, err
+	}
+	before := auditState(w.ToDto())
+	// keep the stored secret if the client did not send a new one (it is never exposed via the api)
+	if newWatchfolder.S3 != nil && newWatchfolder.S3.SecretKey == "" && w.S3 != nil {
+		newWatchfolder.S3.SecretKey = w.S3.SecretKey
+	}
+	preset, err := PresetService().EffectivePreset(newWatchfolder.Preset)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := resolveParameters(preset.Parameters, newWatchfolder.Params); err != nil {
+		return nil, err
+	}
+	if err := validateWatchfolder(newWatchfolder); err != nil {
+		return nil, err
+	}
 
 	w.Name = newWatchfolder.Name
 	w.Description = newWatchfolder.Description
+	w.Type = newWatchfolder.Type
+	w.S3 = newWatchfolder.S3
 	w.Path = newWatchfolder.Path
 	w.Preset = newWatchfolder.Preset
+	w.Params = newWatchfolder.Params
 	w.GrowthChecks = newWatchfolder.GrowthChecks
 	w.Interval = newWatchfolder.Interval
 	w.Filter = newWatchfolder.Filter
+	w.Trigger = newWatchfolder.Trigger
+	w.Schedule = newWatchfolder.Schedule
 	w.Suspended = newWatchfolder.Suspended
+	w.Managed = actor.IsConfig()
 
 	watchfolderUpdates <- w
 
 	s.sev.Metrics().Gauge("watchfolder.updated").Inc()
 
-	return s.UpdateWatchfolderInternal(w)
+	w, err = s.UpdateWatchfolderInternal(w)
+	if err == nil {
+		AuditService().Record(dto.AUDIT_WATCHFOLDER, dto.AUDIT_UPDATE, w.Uuid, before, auditState(w.ToDto()), actor)
+	}
+	return w, err
+}
+
+func validateWatchfolder(newWatchfolder *dto.NewWatchfolder) error {
+	if newWatchfolder.Type == "" {
+		newWatchfolder.Type = dto.WATCHFOLDER_LOCAL
+	}
+	switch newWatchfolder.Type {
+	case dto.WATCHFOLDER_LOCAL:
+		if err := sandbox.ValidateWatchfolder(newWatchfolder.Path); err != nil {
+			return err
+		}
+	case dto.WATCHFOLDER_S3:
+		if newWatchfolder.S3 == nil || newWatchfolder.S3.Endpoint == "" || newWatchfolder.S3.Bucket == "" {
+			return errors.New("s3 watchfolder requires an endpoint and a bucket")
+		}
+		switch newWatchfolder.S3.Mode {
+		case "":
+			newWatchfolder.S3.Mode = dto.S3_DOWNLOAD
+		case dto.S3_DOWNLOAD, dto.S3_PRESIGN:
+		default:
+			return fmt.Errorf("invalid s3 mode '%s' (expected '%s' or '%s')", newWatchfolder.S3.Mode, dto.S3_DOWNLOAD, dto.S3_PRESIGN)
+		}
+		if newWatchfolder.S3.Mode == dto.S3_DOWNLOAD {
+			// downloads become task inputs, the default scratch directory in the temp dir is usually not an input root
+			if newWatchfolder.S3.ScratchDir == "" && len(config.Config().InputRoots) > 0 {
+				return errors.New("s3 watchfolder requires a scratchDir inside the allowed input directories")
+			}
+			if err := sandbox.ValidateWatchfolder(newWatchfolder.S3.ScratchDir); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid watchfolder type '%s' (expected '%s' or '%s')", newWatchfolder.Type, dto.WATCHFOLDER_LOCAL, dto.WATCHFOLDER_S3)
+	}
+
+	if newWatchfolder.Trigger != nil && len(newWatchfolder.Trigger.Extensions) == 0 {
+		return errors.New("watchfolder trigger requires at least one extension")
+	}
+
+	return schedule.Validate(newWatchfolder.Schedule)
 }
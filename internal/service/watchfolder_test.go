@@ -3,6 +3,8 @@ package service
 import (
 	"testing"
 
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/metrics"
@@ -17,7 +19,7 @@ func setupWatchfolderTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -50,7 +52,7 @@ func TestWatchfolderService(t *testing.T) {
 	preset, err := PresetService().NewPreset(&dto.NewPreset{
 		Name:    "Test Preset",
 		Command: "test command",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Failed to create preset: %v", err)
 	}
@@ -61,7 +63,7 @@ func TestWatchfolderService(t *testing.T) {
 			Preset: preset.Uuid,
 		}
 
-		wf, err := WatchfolderService().NewWatchfolder(newWatchfolder)
+		wf, err := WatchfolderService().NewWatchfolder(newWatchfolder, nil)
 		if err != nil {
 			t.Fatalf("Failed to create watchfolder: %v", err)
 		}
@@ -76,6 +78,42 @@ func TestWatchfolderService(t *testing.T) {
 		}
 	})
 
+	t.Run("Reject invalid s3 watchfolder", func(t *testing.T) {
+		_, err := WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{
+			Type:   dto.WATCHFOLDER_S3,
+			S3:     &dto.WatchfolderS3{S3Connection: dto.S3Connection{Endpoint: "localhost:9000"}},
+			Preset: preset.Uuid,
+		}, nil)
+		if err == nil {
+			t.Error("Expected error when creating s3 watchfolder without bucket")
+		}
+	})
+
+	t.Run("Reject trigger without extensions", func(t *testing.T) {
+		_, err := WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{
+			Path:    "/test/watch",
+			Trigger: &dto.WatchfolderTrigger{SidecarExtensions: []string{"xml"}},
+			Preset:  preset.Uuid,
+		}, nil)
+		if err == nil {
+			t.Error("Expected error when creating triggered watchfolder without trigger extensions")
+		}
+	})
+
+	t.Run("Reject untyped watchfolder outside the input roots", func(t *testing.T) {
+		viper.Set("inputRoots", []string{t.TempDir()})
+		config.Init()
+		defer func() {
+			viper.Set("inputRoots", []string{})
+			config.Init()
+		}()
+
+		_, err := WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{Path: "/etc", Preset: preset.Uuid}, nil)
+		if err == nil {
+			t.Error("Expected error when creating watchfolder without type outside the input roots")
+		}
+	})
+
 	t.Run("List watchfolders", func(t *testing.T) {
 		wfs, total, err := WatchfolderService().ListWatchfolders(0, 10)
 		if err != nil {
@@ -110,7 +148,7 @@ func TestWatchfolderService(t *testing.T) {
 		wfs, _, _ := WatchfolderService().ListWatchfolders(0, 1)
 		wf := (*wfs)[0]
 
-		err := WatchfolderService().DeleteWatchfolder(wf.Uuid)
+		err := WatchfolderService().DeleteWatchfolder(wf.Uuid, nil)
 		if err != nil {
 			t.Fatalf("Failed to delete watchfolder: %v", err)
 		}
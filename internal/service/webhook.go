@@ -2,24 +2,32 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/sev"
+	"github.com/yosev/debugo"
 )
 
+var debugWebhook = debugo.New("webhook:service")
+
 type webhookSvc struct {
 	service
-	sev               *sev.Sev
-	webhookRepository *repository.Webhook
+	sev                       *sev.Sev
+	webhookRepository         *repository.Webhook
+	webhookDeliveryRepository *repository.WebhookDelivery
+	dispatcher                *webhookDispatcher
 }
 
 func (s *webhookSvc) ListWebhooks(page int, perPage int) (*[]model.Webhook, int64, error) {
 	return s.webhookRepository.List(page, perPage)
 }
 
-func (s *webhookSvc) DeleteWebhook(uuid string) error {
+func (s *webhookSvc) DeleteWebhook(uuid string, actor *dto.Actor) error {
 	w, err := s.webhookRepository.First(uuid)
 	if err != nil {
 		return err
@@ -28,6 +36,9 @@ func (s *webhookSvc) DeleteWebhook(uuid string) error {
 	if w.Uuid == "" {
 		return errors.New("webhook for given uuid not found")
 	}
+	if err := checkManaged(dto.AUDIT_WEBHOOK, w.Managed, actor); err != nil {
+		return err
+	}
 
 	err = s.webhookRepository.Delete(w)
 	if err != nil {
@@ -36,28 +47,216 @@ func (s *webhookSvc) DeleteWebhook(uuid string) error {
 	}
 
 	s.sev.Logger().Infof("deleted webhook for event %s (uuid: %s)", w.Event, w.Uuid)
+	AuditService().Record(dto.AUDIT_WEBHOOK, dto.AUDIT_DELETE, w.Uuid, auditState(w.ToDto()), nil, actor)
 
 	s.sev.Metrics().Gauge("webhook.deleted").Inc()
-	s.Fire(dto.WEBHOOK_DELETED, w)
+	s.Fire(dto.WEBHOOK_DELETED, w.ToDto())
 
 	return nil
 }
 
-func (s *webhookSvc) NewWebhook(webhook *dto.NewWebhook) (*model.Webhook, error) {
-	w, err := s.webhookRepository.Create(webhook.Event, webhook.Url)
+func (s *webhookSvc) NewWebhook(webhook *dto.NewWebhook, actor *dto.Actor) (*model.Webhook, error) {
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+
+	w, err := s.webhookRepository.Create(webhook, actor.IsConfig())
+	if err != nil {
+		return nil, err
+	}
 	s.sev.Logger().Infof("created new webhook for event %s (uuid: %s)", w.Event, w.Uuid)
+	AuditService().Record(dto.AUDIT_WEBHOOK, dto.AUDIT_CREATE, w.Uuid, nil, auditState(w.ToDto()), actor)
 
 	s.sev.Metrics().Gauge("webhook.created").Inc()
-	s.Fire(dto.WEBHOOK_CREATED, w)
+	s.Fire(dto.WEBHOOK_CREATED, w.ToDto())
 
-	return w, err
+	return w, nil
+}
+
+// UpdateWebhook replaces a webhook in place, so it is kept unchanged if the new one is invalid
+func (s *webhookSvc) UpdateWebhook(uuid string, webhook *dto.NewWebhook, actor *dto.Actor) (*model.Webhook, error) {
+	w, err := s.webhookRepository.First(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Uuid == "" {
+		return nil, errors.New("webhook for given uuid not found")
+	}
+	if err := checkManaged(dto.AUDIT_WEBHOOK, w.Managed, actor); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	before := auditState(w.ToDto())
+
+	w.Event = webhook.Event
+	w.Url = webhook.Url
+	w.Secret = webhook.Secret
+	w.Filter = webhook.Filter
+	w.Headers = webhook.Headers
+	w.Template = webhook.Template
+	w.ContentType = webhook.ContentType
+	w.Managed = actor.IsConfig()
+
+	w, err = s.webhookRepository.Update(w)
+	if err != nil {
+		s.sev.Logger().Warnf("failed to update webhook for event %s (uuid: %s): %+v", w.Event, w.Uuid, err)
+		return nil, err
+	}
+
+	s.sev.Logger().Infof("updated webhook for event %s (uuid: %s)", w.Event, w.Uuid)
+	AuditService().Record(dto.AUDIT_WEBHOOK, dto.AUDIT_UPDATE, w.Uuid, before, auditState(w.ToDto()), actor)
+
+	return w, nil
+}
+
+func validateWebhook(webhook *dto.NewWebhook) error {
+	if webhook.Template != "" {
+		if _, err := sev.ParseWebhookTemplate(webhook.Template); err != nil {
+			return fmt.Errorf("invalid template: %v", err)
+		}
+	}
+	return nil
 }
 
 func (s *webhookSvc) Fire(event dto.WebhookEvent, data interface{}) error {
+	return s.fire(event, data, false)
+}
+
+// FireProgress fires an event for an update that only changed the progress of a task
+func (s *webhookSvc) FireProgress(event dto.WebhookEvent, data interface{}) error {
+	return s.fire(event, data, true)
+}
+
+func (s *webhookSvc) fire(event dto.WebhookEvent, data interface{}, progress bool) error {
 	webhooks, err := s.webhookRepository.ListByEvent(event)
+	if err != nil {
+		return err
+	}
+
 	for _, webhook := range *webhooks {
-		go s.sev.FireWebhook(&webhook, data)
-		s.sev.Metrics().Gauge("webhook.executed").Inc()
+		if !matchesFilter(webhook.Filter, data, progress) {
+			debugWebhook.Debugf("skipped webhook for event '%s' due to its filter (uuid: %s)", webhook.Event, webhook.Uuid)
+			continue
+		}
+		payload, err := s.sev.WebhookPayload(&webhook, data)
+		if err != nil {
+			s.sev.Logger().Warnf("failed to fire webhook for event '%s' (uuid: %s) due to payload problems: %+v", webhook.Event, webhook.Uuid, err)
+			continue
+		}
+		s.deliver(&webhook, payload, progress)
+	}
+	return nil
+}
+
+func (s *webhookSvc) ListDeliveries(webhookUuid string, page int, perPage int) (*[]model.WebhookDelivery, int64, error) {
+	w, err := s.webhookRepository.First(webhookUuid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if w.Uuid == "" {
+		return nil, 0, errors.New("webhook for given uuid not found")
+	}
+
+	return s.webhookDeliveryRepository.ListByWebhook(w.Uuid, page, perPage)
+}
+
+// Redeliver sends the payload of a previous delivery again as new delivery
+func (s *webhookSvc) Redeliver(webhookUuid string, deliveryUuid string) (*model.WebhookDelivery, error) {
+	w, err := s.webhookRepository.First(webhookUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Uuid == "" {
+		return nil, errors.New("webhook for given uuid not found")
+	}
+
+	d, err := s.webhookDeliveryRepository.First(w.Uuid, deliveryUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Uuid == "" {
+		return nil, errors.New("delivery for given uuid not found")
+	}
+
+	s.sev.Logger().Infof("redelivering webhook for event %s (uuid: %s delivery: %s)", w.Event, w.Uuid, d.Uuid)
+	return s.deliver(w, []byte(d.Payload), false)
+}
+
+// deliver persists a new delivery which is sent in order by the dispatcher
+func (s *webhookSvc) deliver(webhook *model.Webhook, payload []byte, progress bool) (*model.WebhookDelivery, error) {
+	delivery, err := s.dispatcher.enqueue(webhook, payload, progress)
+	if err != nil {
+		s.sev.Logger().Warnf("failed to persist webhook delivery for event '%s' (uuid: %s): %+v", webhook.Event, webhook.Uuid, err)
+	}
+	return delivery, err
+}
+
+// matchesFilter reports whether the event data passes the webhook filter, a batch matches if any of its tasks does
+func matchesFilter(filter *dto.WebhookFilter, data interface{}, progress bool) bool {
+	if filter == nil {
+		return true
+	}
+	if progress && filter.SkipProgress {
+		return false
+	}
+
+	switch d := data.(type) {
+	case *dto.Task:
+		return matchesTaskFilter(filter, d)
+	case []dto.Task:
+		for i := range d {
+			if matchesTaskFilter(filter, &d[i]) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesTaskFilter(filter *dto.WebhookFilter, task *dto.Task) bool {
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, task.Status) {
+		return false
+	}
+	if len(filter.Presets) > 0 && !slices.Contains(filter.Presets, task.Preset) {
+		return false
+	}
+	if len(filter.Watchfolders) > 0 && !slices.Contains(filter.Watchfolders, task.Watchfolder) {
+		return false
+	}
+	if len(filter.Batches) > 0 && !slices.Contains(filter.Batches, task.Batch) {
+		return false
+	}
+	for key, value := range filter.Metadata {
+		if task.Metadata == nil {
+			return false
+		}
+		v, ok := lookupMetadata(map[string]interface{}(*task.Metadata), key)
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupMetadata resolves a dot separated path like "show.episode" in nested metadata
+func lookupMetadata(metadata map[string]interface{}, key string) (interface{}, bool) {
+	var current interface{} = metadata
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
 	}
-	return err
+	return current, true
 }
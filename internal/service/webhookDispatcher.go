@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+const (
+	// number of deliveries sent in parallel, deliveries to the same url are always sent one after another
+	webhookWorkers = 4
+	// pending deliveries per webhook, further deliveries are dropped until the target catches up
+	maxPendingDeliveries = 10000
+	// pending deliveries are looked up periodically in case a wake up was missed
+	dispatchInterval = 1 * time.Second
+	// finished deliveries older than the configured retention are deleted periodically
+	pruneInterval = 1 * time.Hour
+)
+
+// webhookDispatcher sends the pending deliveries stored in the database. Deliveries survive restarts
+// as they stay pending until their final attempt finished.
+type webhookDispatcher struct {
+	sev                       *sev.Sev
+	webhookRepository         *repository.Webhook
+	webhookDeliveryRepository *repository.WebhookDelivery
+
+	mu   sync.Mutex
+	busy map[string]bool // urls with a delivery in flight
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+}
+
+func newWebhookDispatcher(s *sev.Sev, webhookRepository *repository.Webhook, webhookDeliveryRepository *repository.WebhookDelivery) *webhookDispatcher {
+	return &webhookDispatcher{
+		sev:                       s,
+		webhookRepository:         webhookRepository,
+		webhookDeliveryRepository: webhookDeliveryRepository,
+		busy:                      map[string]bool{},
+		wake:                      make(chan struct{}, webhookWorkers),
+	}
+}
+
+func (d *webhookDispatcher) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	for i := 0; i < webhookWorkers; i++ {
+		go d.work(ctx)
+	}
+	go d.pruneLoop(ctx)
+}
+
+func (d *webhookDispatcher) stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// notify wakes up an idle worker without ever blocking the caller
+func (d *webhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue persists a new pending delivery, if the webhook has too many pending deliveries it is recorded as failed.
+// Progress updates are not persisted while the webhook has pending deliveries, the receiver gets a newer one
+// once it caught up. A nil delivery is returned for them.
+func (d *webhookDispatcher) enqueue(webhook *model.Webhook, payload []byte, progress bool) (*model.WebhookDelivery, error) {
+	pending, _ := d.webhookDeliveryRepository.CountPending(webhook.Uuid)
+	if progress && pending > 0 {
+		debugWebhook.Debugf("skipped progress delivery for event '%s' as previous deliveries are pending (uuid: %s)", webhook.Event, webhook.Uuid)
+		return nil, nil
+	}
+	if pending >= maxPendingDeliveries {
+		d.sev.Logger().Warnf("dropped webhook delivery for event '%s' (uuid: %s): too many pending deliveries", webhook.Event, webhook.Uuid)
+		d.sev.Metrics().Gauge("webhook.failed").Inc()
+		return d.webhookDeliveryRepository.Create(webhook, payload, dto.DELIVERY_FAILED, "too many pending deliveries")
+	}
+
+	delivery, err := d.webhookDeliveryRepository.Create(webhook, payload, dto.DELIVERY_PENDING, "")
+	if err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return delivery, nil
+}
+
+func (d *webhookDispatcher) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		d.prune()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune deletes finished deliveries older than the configured retention, a retention of 0 keeps them forever
+func (d *webhookDispatcher) prune() {
+	retention := config.Config().WebhookDeliveryRetention
+	if retention <= 0 {
+		return
+	}
+	deleted, err := d.webhookDeliveryRepository.DeleteFinishedBefore(time.Now().Add(-retention))
+	if err != nil {
+		d.sev.Logger().Warnf("failed to prune webhook deliveries: %+v", err)
+		return
+	}
+	if deleted > 0 {
+		debugWebhook.Debugf("pruned %d webhook deliveries older than %s", deleted, retention)
+	}
+}
+
+func (d *webhookDispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.dispatchNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchNext sends the oldest pending delivery to an url that is not busy and reports whether one was found.
+// Keying by url keeps events of different webhooks pointing to the same receiver in order, too.
+func (d *webhookDispatcher) dispatchNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	d.mu.Lock()
+	busy := make([]string, 0, len(d.busy))
+	for url := range d.busy {
+		busy = append(busy, url)
+	}
+	delivery, err := d.webhookDeliveryRepository.NextPending(busy)
+	if err != nil || delivery == nil {
+		d.mu.Unlock()
+		return false
+	}
+	d.busy[delivery.Url] = true
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.busy, delivery.Url)
+		d.mu.Unlock()
+		// the next delivery to this url may now be sent by any worker
+		d.notify()
+	}()
+
+	d.send(delivery)
+	return true
+}
+
+// send delivers the payload, every attempt is recorded in the delivery log
+func (d *webhookDispatcher) send(delivery *model.WebhookDelivery) {
+	webhook, err := d.webhookRepository.First(delivery.Webhook)
+	if err == nil && webhook.Uuid == "" {
+		err = errors.New("webhook has been deleted")
+	}
+
+	if err == nil {
+		err = d.sev.DeliverWebhook(webhook, delivery.Uuid, []byte(delivery.Payload), func(attempt *sev.WebhookAttempt) {
+			delivery.Attempts = attempt.Attempt
+			delivery.StatusCode = attempt.Status
+			delivery.Latency = attempt.Latency.Milliseconds()
+			delivery.Response = attempt.Response
+			delivery.Error = ""
+			if attempt.Error != nil {
+				delivery.Error = attempt.Error.Error()
+			}
+			d.webhookDeliveryRepository.Update(delivery)
+		})
+	}
+
+	if err != nil {
+		delivery.Status = dto.DELIVERY_FAILED
+		delivery.Error = err.Error()
+		d.sev.Metrics().Gauge("webhook.failed").Inc()
+	} else {
+		delivery.Status = dto.DELIVERY_SUCCESSFUL
+	}
+	d.webhookDeliveryRepository.Update(delivery)
+	d.sev.Metrics().Gauge("webhook.executed").Inc()
+}
@@ -1,9 +1,19 @@
 package service
 
 import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/sev"
 	"gorm.io/driver/sqlite"
@@ -16,7 +26,7 @@ func setupWebhookTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -42,7 +52,7 @@ func TestWebhookService(t *testing.T) {
 			Url:   "http://localhost:8080/webhook",
 		}
 
-		_, err := WebhookService().NewWebhook(newWebhook)
+		_, err := WebhookService().NewWebhook(newWebhook, nil)
 		if err != nil {
 			t.Fatalf("Failed to create webhook: %v", err)
 		}
@@ -67,12 +77,12 @@ func TestWebhookService(t *testing.T) {
 			Url:   "http://localhost:8080/webhook",
 		}
 
-		webhook, err := WebhookService().NewWebhook(newWebhook)
+		webhook, err := WebhookService().NewWebhook(newWebhook, nil)
 		if err != nil {
 			t.Fatalf("Failed to create webhook: %v", err)
 		}
 
-		err = WebhookService().DeleteWebhook(webhook.Uuid)
+		err = WebhookService().DeleteWebhook(webhook.Uuid, nil)
 		if err != nil {
 			t.Fatalf("Failed to delete webhook: %v", err)
 		}
@@ -86,4 +96,280 @@ func TestWebhookService(t *testing.T) {
 			t.Errorf("Expected 1 webhook, got %d", total)
 		}
 	})
+
+	t.Run("Retry failed delivery", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.BATCH_CREATED, Url: server.URL}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+		WebhookService().Fire(dto.BATCH_CREATED, map[string]string{"uuid": "batch"})
+
+		var delivery model.WebhookDelivery
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			deliveries, _, _ := WebhookService().ListDeliveries(webhook.Uuid, 0, 10)
+			if len(*deliveries) == 1 && (*deliveries)[0].Status != dto.DELIVERY_PENDING {
+				delivery = (*deliveries)[0]
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		if delivery.Status != dto.DELIVERY_SUCCESSFUL {
+			t.Fatalf("Expected delivery to succeed, got %s", delivery.Status)
+		}
+		if delivery.Attempts != 2 || delivery.StatusCode != 200 || delivery.Response != "ok" {
+			t.Errorf("Expected second attempt to succeed, got %+v", delivery)
+		}
+	})
+
+	t.Run("Sign delivery", func(t *testing.T) {
+		signatures := make(chan bool, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			timestamp, _ := strconv.ParseInt(r.Header.Get("X-FFmate-Timestamp"), 10, 64)
+			signatures <- r.Header.Get("X-FFmate-Signature") == "sha256="+sev.SignWebhook("s3cr3t", timestamp, body) && r.Header.Get("X-FFmate-Delivery") != ""
+		}))
+		defer server.Close()
+
+		webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.BATCH_FINISHED, Url: server.URL, Secret: "s3cr3t"}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+		if !webhook.ToDto().Signed {
+			t.Error("Expected webhook to be signed")
+		}
+
+		WebhookService().Fire(dto.BATCH_FINISHED, map[string]string{"uuid": "batch"})
+		select {
+		case valid := <-signatures:
+			if !valid {
+				t.Error("Expected a valid signature")
+			}
+		case <-time.After(time.Second):
+			t.Error("Timeout waiting for signed delivery")
+		}
+	})
+
+	t.Run("Send custom headers", func(t *testing.T) {
+		headers := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers <- r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+
+		webhook, _ := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.PRESET_CREATED, Url: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}, nil)
+		if webhook.ToDto().Headers["Authorization"] == "Bearer token" {
+			t.Error("Expected header values to be masked")
+		}
+
+		WebhookService().Fire(dto.PRESET_CREATED, map[string]string{"uuid": "preset"})
+		select {
+		case header := <-headers:
+			if header != "Bearer token" {
+				t.Errorf("Expected Authorization header 'Bearer token', got '%s'", header)
+			}
+		case <-time.After(time.Second):
+			t.Error("Timeout waiting for delivery")
+		}
+	})
+
+	t.Run("Render payload template", func(t *testing.T) {
+		type delivery struct {
+			contentType string
+			body        string
+		}
+		deliveries := make(chan delivery, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			deliveries <- delivery{contentType: r.Header.Get("Content-Type"), body: string(body)}
+		}))
+		defer server.Close()
+
+		_, err := WebhookService().NewWebhook(&dto.NewWebhook{
+			Event:       dto.PRESET_DELETED,
+			Url:         server.URL,
+			Template:    `{"text": {{ printf "%s was deleted" .data.name | json }}}`,
+			ContentType: "application/vnd.slack+json",
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+
+		WebhookService().Fire(dto.PRESET_DELETED, &dto.Preset{Name: "H.264 \"proxy\""})
+		select {
+		case d := <-deliveries:
+			if d.body != `{"text": "H.264 \"proxy\" was deleted"}` {
+				t.Errorf("Unexpected rendered payload %s", d.body)
+			}
+			if d.contentType != "application/vnd.slack+json" {
+				t.Errorf("Expected custom content type, got %s", d.contentType)
+			}
+		case <-time.After(time.Second):
+			t.Error("Timeout waiting for delivery")
+		}
+	})
+
+	t.Run("Reject invalid template", func(t *testing.T) {
+		_, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_UPDATED, Url: "http://localhost:8080/webhook", Template: "{{ .data.name "}, nil)
+		if err == nil {
+			t.Error("Expected error for invalid template")
+		}
+	})
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	db, s := setupWebhookTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.TASK_UPDATED, Url: server.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	t.Run("Send pending deliveries after restart in order", func(t *testing.T) {
+		// deliveries left pending by a previous run
+		deliveryRepository := &repository.WebhookDelivery{DB: db}
+		for i := 0; i < 5; i++ {
+			deliveryRepository.Create(webhook, []byte(strconv.Itoa(i)), dto.DELIVERY_PENDING, "")
+		}
+
+		Init(s)
+		for i := 0; i < 5; i++ {
+			select {
+			case body := <-received:
+				if body != strconv.Itoa(i) {
+					t.Fatalf("Expected delivery %d, got %s", i, body)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timeout waiting for delivery %d", i)
+			}
+		}
+	})
+
+	t.Run("Keep order of fired events", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			WebhookService().Fire(dto.TASK_UPDATED, &dto.Task{Uuid: strconv.Itoa(i)})
+		}
+		for i := 0; i < 5; i++ {
+			select {
+			case body := <-received:
+				var payload struct {
+					Data dto.Task `json:"data"`
+				}
+				json.Unmarshal([]byte(body), &payload)
+				if payload.Data.Uuid != strconv.Itoa(i) {
+					t.Fatalf("Expected delivery %d, got %s", i, body)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timeout waiting for delivery %d", i)
+			}
+		}
+	})
+
+	t.Run("Skip progress while deliveries are pending", func(t *testing.T) {
+		block := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		}))
+		defer slow.Close()
+		defer close(block)
+
+		webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.BATCH_FINISHED, Url: slow.URL}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+		WebhookService().Fire(dto.BATCH_FINISHED, map[string]string{"uuid": "batch"})
+		WebhookService().FireProgress(dto.BATCH_FINISHED, map[string]string{"uuid": "batch"})
+		WebhookService().FireProgress(dto.BATCH_FINISHED, map[string]string{"uuid": "batch"})
+
+		if _, total, _ := WebhookService().ListDeliveries(webhook.Uuid, 0, 10); total != 1 {
+			t.Errorf("Expected progress updates not to be persisted, got %d deliveries", total)
+		}
+	})
+
+	t.Run("Prune finished deliveries", func(t *testing.T) {
+		webhook, err := WebhookService().NewWebhook(&dto.NewWebhook{Event: dto.BATCH_CREATED, Url: "http://localhost:8080/prune"}, nil)
+		if err != nil {
+			t.Fatalf("Failed to create webhook: %v", err)
+		}
+		deliveryRepository := &repository.WebhookDelivery{DB: db}
+		deliveryRepository.Create(webhook, []byte("{}"), dto.DELIVERY_SUCCESSFUL, "")
+		deliveryRepository.Create(webhook, []byte("{}"), dto.DELIVERY_FAILED, "")
+		recent, _ := deliveryRepository.Create(webhook, []byte("{}"), dto.DELIVERY_SUCCESSFUL, "")
+		db.Model(&model.WebhookDelivery{}).Where("webhook = ? AND uuid != ?", webhook.Uuid, recent.Uuid).UpdateColumn("updated_at", time.Now().Add(-48*time.Hour))
+
+		viper.Set("webhookDeliveryRetention", "24h")
+		config.Init()
+		defer func() {
+			viper.Set("webhookDeliveryRetention", "0s")
+			config.Init()
+		}()
+
+		WebhookService().dispatcher.prune()
+		deliveries, _, _ := WebhookService().ListDeliveries(webhook.Uuid, 0, 10)
+		if len(*deliveries) != 1 || (*deliveries)[0].Uuid != recent.Uuid {
+			t.Errorf("Expected only the recent delivery to be kept, got %+v", *deliveries)
+		}
+	})
+}
+
+func TestMatchesFilter(t *testing.T) {
+	task := &dto.Task{
+		Status:      dto.DONE_ERROR,
+		Preset:      "preset-uuid",
+		Watchfolder: "watchfolder-uuid",
+		Batch:       "batch-uuid",
+		Metadata:    &dto.InterfaceMap{"show": map[string]interface{}{"episode": float64(12)}},
+	}
+
+	tests := []struct {
+		name     string
+		filter   *dto.WebhookFilter
+		data     interface{}
+		progress bool
+		want     bool
+	}{
+		{name: "No filter", filter: nil, data: task, want: true},
+		{name: "Status", filter: &dto.WebhookFilter{Statuses: []dto.TaskStatus{dto.DONE_ERROR}}, data: task, want: true},
+		{name: "Other status", filter: &dto.WebhookFilter{Statuses: []dto.TaskStatus{dto.DONE_SUCCESSFUL}}, data: task, want: false},
+		{name: "Preset", filter: &dto.WebhookFilter{Presets: []string{"preset-uuid"}}, data: task, want: true},
+		{name: "Other watchfolder", filter: &dto.WebhookFilter{Watchfolders: []string{"other"}}, data: task, want: false},
+		{name: "Batch", filter: &dto.WebhookFilter{Batches: []string{"batch-uuid"}}, data: task, want: true},
+		{name: "Nested metadata", filter: &dto.WebhookFilter{Metadata: map[string]string{"show.episode": "12"}}, data: task, want: true},
+		{name: "Missing metadata", filter: &dto.WebhookFilter{Metadata: map[string]string{"show.season": "1"}}, data: task, want: false},
+		{name: "Skip progress", filter: &dto.WebhookFilter{SkipProgress: true}, data: task, progress: true, want: false},
+		{name: "Keep status change", filter: &dto.WebhookFilter{SkipProgress: true}, data: task, want: true},
+		{name: "Batch with matching task", filter: &dto.WebhookFilter{Statuses: []dto.TaskStatus{dto.DONE_ERROR}}, data: []dto.Task{{Status: dto.QUEUED}, *task}, want: true},
+		{name: "Non task payload", filter: &dto.WebhookFilter{Statuses: []dto.TaskStatus{dto.DONE_ERROR}}, data: &dto.Preset{}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(tt.filter, tt.data, tt.progress); got != tt.want {
+				t.Errorf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/gorilla/websocket"
+	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/yosev/debugo"
 )
 
@@ -18,6 +19,14 @@ const (
 	TASK_UPDATED Subject = "task:updated"
 	TASK_DELETED Subject = "task:deleted"
 
+	TASK_STARTED           Subject = "task:started"
+	TASK_SUCCEEDED         Subject = "task:succeeded"
+	TASK_FAILED            Subject = "task:failed"
+	TASK_CANCELED          Subject = "task:canceled"
+	TASK_RETRYING          Subject = "task:retrying"
+	PRE_PROCESSING_FAILED  Subject = "preProcessing:failed"
+	POST_PROCESSING_FAILED Subject = "postProcessing:failed"
+
 	PRESET_CREATED Subject = "preset:created"
 	PRESET_UPDATED Subject = "preset:updated"
 	PRESET_DELETED Subject = "preset:deleted"
@@ -25,10 +34,13 @@ const (
 	WATCHFOLDER_CREATED Subject = "watchfolder:created"
 	WATCHFOLDER_UPDATED Subject = "watchfolder:updated"
 	WATCHFOLDER_DELETED Subject = "watchfolder:deleted"
+	WATCHFOLDER_ERROR   Subject = "watchfolder:error"
 
 	BATCH_CREATED  Subject = "batch:created"
 	BATCH_FINISHED Subject = "batch:finished"
 
+	AUDIT_CREATED Subject = "audit:created"
+
 	LOG Subject = "log"
 )
 
@@ -39,15 +51,21 @@ type message struct {
 
 var debug = debugo.New("websocket:service")
 
+// connection is an open websocket, auth is nil if authentication is disabled
+type connection struct {
+	conn *websocket.Conn
+	auth *dto.Auth
+}
+
 var (
-	conns = make(map[string]*websocket.Conn)
+	conns = make(map[string]*connection)
 	mutex = sync.RWMutex{}
 )
 
-func (s *websocketSvc) AddConnection(uuid string, conn *websocket.Conn) {
+func (s *websocketSvc) AddConnection(uuid string, conn *websocket.Conn, auth *dto.Auth) {
 	mutex.Lock()
 	defer mutex.Unlock()
-	conns[uuid] = conn
+	conns[uuid] = &connection{conn: conn, auth: auth}
 }
 
 func (s *websocketSvc) RemoveConnection(uuid string, conn *websocket.Conn) {
@@ -59,8 +77,20 @@ func (s *websocketSvc) RemoveConnection(uuid string, conn *websocket.Conn) {
 func (s *websocketSvc) Broadcast(subject Subject, msg any) error {
 	mutex.Lock()
 	defer mutex.Unlock()
-	for _, conn := range conns {
-		conn.WriteJSON(&message{Subject: subject, Payload: msg})
+	for _, c := range conns {
+		c.conn.WriteJSON(&message{Subject: subject, Payload: msg})
+	}
+	return nil
+}
+
+// BroadcastPermitted sends the message only to connections whose caller has the given permission
+func (s *websocketSvc) BroadcastPermitted(subject Subject, permission dto.Permission, msg any) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, c := range conns {
+		if c.auth == nil || c.auth.Can(permission) {
+			c.conn.WriteJSON(&message{Subject: subject, Payload: msg})
+		}
 	}
 	return nil
 }
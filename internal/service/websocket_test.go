@@ -0,0 +1,76 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestWebsocketService(t *testing.T) {
+	db, _ := setupBundleTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	auths := map[string]*dto.Auth{
+		"viewer":   {User: "viewer", Name: "viewer", Role: dto.ROLE_VIEWER},
+		"admin":    {User: "admin", Name: "admin", Role: dto.ROLE_ADMIN},
+		"disabled": nil,
+	}
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		name := r.URL.Query().Get("name")
+		WebsocketService().AddConnection(name, conn, auths[name])
+		defer WebsocketService().RemoveConnection(name, conn)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	clients := map[string]*websocket.Conn{}
+	for name := range auths {
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?name="+name, nil)
+		if err != nil {
+			t.Fatalf("Failed to open websocket connection: %v", err)
+		}
+		defer ws.Close()
+		clients[name] = ws
+	}
+	// wait for all connections to be registered
+	for i := 0; i < 100; i++ {
+		mutex.RLock()
+		registered := len(conns)
+		mutex.RUnlock()
+		if registered >= len(auths) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Run("Push audit entries to permitted clients only", func(t *testing.T) {
+		AuditService().Record(dto.AUDIT_PRESET, dto.AUDIT_CREATE, "websocket-preset", nil, dto.InterfaceMap{"name": "Websocket"}, nil)
+
+		for name, ws := range clients {
+			var msg message
+			ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			received := ws.ReadJSON(&msg) == nil && msg.Subject == AUDIT_CREATED
+			if expected := name != "viewer"; received != expected {
+				t.Errorf("Expected %s to receive audit entry: %t, got %t", name, expected, received)
+			}
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/yosev/debugo"
+)
+
+var debug = debugo.New("storage:s3")
+
+// S3 is a thin wrapper around an S3-compatible bucket (AWS, MinIO, R2, ...)
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+func NewS3(connection *dto.S3Connection) (*S3, error) {
+	if connection == nil || connection.Endpoint == "" || connection.Bucket == "" {
+		return nil, errors.New("s3 connection requires an endpoint and a bucket")
+	}
+
+	creds := credentials.NewStaticV4(connection.AccessKey, connection.SecretKey, "")
+	if connection.AccessKey == "" {
+		// fall back to credentials from the environment
+		creds = credentials.NewChainCredentials([]credentials.Provider{&credentials.EnvAWS{}, &credentials.EnvMinio{}})
+	}
+
+	client, err := minio.New(connection.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: connection.UseSSL,
+		Region: connection.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{client: client, bucket: connection.Bucket}, nil
+}
+
+// List returns all objects below the given prefix
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, Object{Key: object.Key, Size: object.Size, ETag: object.ETag, LastModified: object.LastModified})
+	}
+	debug.Debugf("listed %d objects (bucket: %s prefix: %s)", len(objects), s.bucket, prefix)
+	return objects, nil
+}
+
+// Download stores the object with the given key at path
+func (s *S3) Download(ctx context.Context, key string, path string) error {
+	err := s.client.FGetObject(ctx, s.bucket, key, path, minio.GetObjectOptions{})
+	if err == nil {
+		debug.Debugf("downloaded object (bucket: %s key: %s) to %s", s.bucket, key, path)
+	}
+	return err
+}
+
+// PresignedUrl returns a url granting read access to the object for the given duration
+func (s *S3) PresignedUrl(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, expires, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Upload stores the file at path under the given key and returns the url of the object.
+// Files larger than partSize are uploaded in parts, a partSize of 0 lets the client decide.
+func (s *S3) Upload(ctx context.Context, key string, path string, partSize uint64) (string, error) {
+	info, err := s.client.FPutObject(ctx, s.bucket, key, path, minio.PutObjectOptions{PartSize: partSize})
+	if err != nil {
+		return "", err
+	}
+	debug.Debugf("uploaded %s to object (bucket: %s key: %s size: %d)", path, s.bucket, key, info.Size)
+	return s.ObjectUrl(key), nil
+}
+
+// ObjectUrl returns the (unsigned) url of the object with the given key
+func (s *S3) ObjectUrl(key string) string {
+	return s.client.EndpointURL().JoinPath(s.bucket, key).String()
+}
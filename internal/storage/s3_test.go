@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server (path style requests only)
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Name     string   `xml:"Name"`
+	Prefix   string   `xml:"Prefix"`
+	KeyCount int      `xml:"KeyCount"`
+	Contents []struct {
+		Key          string `xml:"Key"`
+		Size         int    `xml:"Size"`
+		ETag         string `xml:"ETag"`
+		LastModified string `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *dto.S3Connection) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	return fake, &dto.S3Connection{Endpoint: u.Host, Region: "us-east-1", Bucket: "media", AccessKey: "key", SecretKey: "secret"}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		result := listBucketResult{Name: parts[0], Prefix: r.URL.Query().Get("prefix")}
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, result.Prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			result.Contents = append(result.Contents, struct {
+				Key          string `xml:"Key"`
+				Size         int    `xml:"Size"`
+				ETag         string `xml:"ETag"`
+				LastModified string `xml:"LastModified"`
+			}{Key: k, Size: len(f.objects[k]), ETag: fmt.Sprintf("\"%x\"", len(f.objects[k])), LastModified: time.Now().UTC().Format(time.RFC3339)})
+		}
+		result.KeyCount = len(result.Contents)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		b, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(b)))
+		w.Header().Set("ETag", fmt.Sprintf("\"%x\"", len(b)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	case r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "" && r.URL.Query().Get("uploadId") == "":
+		b, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Decoded-Content-Length") != "" {
+			b = decodeChunked(b)
+		}
+		f.objects[key] = b
+		w.Header().Set("ETag", fmt.Sprintf("\"%x\"", len(b)))
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeChunked strips the chunk signatures of a streaming (aws-chunked) upload
+func decodeChunked(b []byte) []byte {
+	var out []byte
+	for len(b) > 0 {
+		header, rest, _ := strings.Cut(string(b), "\r\n")
+		var size int
+		fmt.Sscanf(header, "%x;", &size)
+		if size == 0 {
+			break
+		}
+		out = append(out, rest[:size]...)
+		b = []byte(rest[size+2:])
+	}
+	return out
+}
+
+func TestS3(t *testing.T) {
+	fake, connection := newFakeS3(t)
+	fake.objects["incoming/show.mp4"] = []byte("video")
+	fake.objects["incoming/nested/clip.mov"] = []byte("clip")
+	fake.objects["archive/old.mp4"] = []byte("old")
+
+	client, err := NewS3(connection)
+	if err != nil {
+		t.Fatalf("Failed to create s3 client: %v", err)
+	}
+
+	t.Run("List objects", func(t *testing.T) {
+		objects, err := client.List(context.Background(), "incoming/")
+		if err != nil {
+			t.Fatalf("Failed to list objects: %v", err)
+		}
+		if len(objects) != 2 {
+			t.Fatalf("Expected 2 objects, got %d", len(objects))
+		}
+		if objects[0].Key != "incoming/nested/clip.mov" || objects[0].Size != 4 {
+			t.Errorf("Unexpected object %+v", objects[0])
+		}
+	})
+
+	t.Run("Download object", func(t *testing.T) {
+		target := filepath.Join(t.TempDir(), "show.mp4")
+		if err := client.Download(context.Background(), "incoming/show.mp4", target); err != nil {
+			t.Fatalf("Failed to download object: %v", err)
+		}
+		b, _ := os.ReadFile(target)
+		if string(b) != "video" {
+			t.Errorf("Expected downloaded content 'video', got '%s'", string(b))
+		}
+	})
+
+	t.Run("Presign object", func(t *testing.T) {
+		u, err := client.PresignedUrl(context.Background(), "incoming/show.mp4", time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to presign object: %v", err)
+		}
+		if !strings.Contains(u, "/media/incoming/show.mp4") || !strings.Contains(u, "X-Amz-Signature=") {
+			t.Errorf("Unexpected presigned url %s", u)
+		}
+	})
+
+	t.Run("Upload object", func(t *testing.T) {
+		source := filepath.Join(t.TempDir(), "out.mp4")
+		os.WriteFile(source, []byte("rendition"), 0644)
+		u, err := client.Upload(context.Background(), "renditions/out.mp4", source, 0)
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		if !strings.HasSuffix(u, "/media/renditions/out.mp4") {
+			t.Errorf("Unexpected object url %s", u)
+		}
+		if string(fake.objects["renditions/out.mp4"]) != "rendition" {
+			t.Errorf("Expected uploaded content 'rendition', got '%s'", string(fake.objects["renditions/out.mp4"]))
+		}
+	})
+
+	t.Run("Missing bucket", func(t *testing.T) {
+		if _, err := NewS3(&dto.S3Connection{Endpoint: connection.Endpoint}); err == nil {
+			t.Error("Expected error for connection without bucket")
+		}
+	})
+}
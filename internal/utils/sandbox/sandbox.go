@@ -0,0 +1,204 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+// Paths are checked twice: when a task, preset or watchfolder is created (Validate*) and again
+// after wildcard resolution right before they are used (Check*). On creation only the part of a
+// path in front of its first wildcard is known, so it must at least be able to end up inside a root.
+// An empty list of roots disables the respective check.
+
+func ValidateInput(raw string) error {
+	return validateTarget("input file", raw, config.Config().InputRoots)
+}
+
+func ValidateOutput(raw string) error {
+	return validateTarget("output file", raw, config.Config().OutputRoots)
+}
+
+func ValidateSidecar(raw string) error {
+	return validate("sidecar file", raw, config.Config().OutputRoots)
+}
+
+// ValidateUpload checks the glob of files a postProcessing upload sends to S3
+func ValidateUpload(raw string) error {
+	return validate("upload file", raw, config.Config().OutputRoots)
+}
+
+// ValidateWatchfolder checks the directory a watchfolder scans or downloads into
+func ValidateWatchfolder(path string) error {
+	return validate("watchfolder path", path, config.Config().InputRoots)
+}
+
+// ValidateScript checks the executable of a pre- or postProcessing script command
+func ValidateScript(raw string) error {
+	executable, err := executable(raw)
+	if err != nil || executable == "" {
+		return err
+	}
+	if !strings.Contains(executable, "${") {
+		return CheckScript(raw)
+	}
+	return validate("script", executable, config.Config().ScriptDirs)
+}
+
+func CheckInput(path string) error {
+	return checkTarget("input file", path, config.Config().InputRoots)
+}
+
+func CheckOutput(path string) error {
+	return checkTarget("output file", path, config.Config().OutputRoots)
+}
+
+func CheckSidecar(path string) error {
+	return check("sidecar file", path, config.Config().OutputRoots)
+}
+
+// CheckUpload checks a resolved upload glob and each file it matched
+func CheckUpload(path string) error {
+	return check("upload file", path, config.Config().OutputRoots)
+}
+
+// CheckScript checks the executable of a resolved script command, executables without path are looked up in $PATH
+func CheckScript(command string) error {
+	roots := config.Config().ScriptDirs
+	if len(roots) == 0 {
+		return nil
+	}
+	executable, err := executable(command)
+	if err != nil || executable == "" {
+		return err
+	}
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return fmt.Errorf("script '%s' not found: %v", executable, err)
+	}
+	return check("script", path, roots)
+}
+
+// validateTarget and checkTarget handle paths ffmpeg opens, urls are left to the command policy
+// and the file protocol is checked as local path
+func validateTarget(kind string, raw string, roots []string) error {
+	if isUrl(raw) {
+		return nil
+	}
+	return validate(kind, localPath(raw), roots)
+}
+
+func checkTarget(kind string, path string, roots []string) error {
+	if isUrl(path) {
+		return nil
+	}
+	return check(kind, localPath(path), roots)
+}
+
+func validate(kind string, raw string, roots []string) error {
+	if len(roots) == 0 || raw == "" {
+		return nil
+	}
+	i := strings.Index(raw, "${")
+	if i < 0 {
+		return check(kind, raw, roots)
+	}
+
+	// only the directory in front of the first wildcard is known yet
+	prefix := raw[:i]
+	if prefix == "" {
+		return nil
+	}
+	dir := prefix
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) && !strings.HasSuffix(prefix, "/") {
+		dir = filepath.Dir(prefix)
+	}
+	dir = realPath(dir)
+	for _, root := range roots {
+		root = realPath(root)
+		if within(dir, root) || within(root, dir) {
+			return nil
+		}
+	}
+	return outsideError(kind, raw, roots)
+}
+
+func check(kind string, path string, roots []string) error {
+	if len(roots) == 0 || path == "" {
+		return nil
+	}
+	real := realPath(path)
+	for _, root := range roots {
+		if within(real, realPath(root)) {
+			return nil
+		}
+	}
+	return outsideError(kind, path, roots)
+}
+
+func outsideError(kind string, path string, roots []string) error {
+	return fmt.Errorf("%s '%s' is outside the allowed directories (%s)", kind, path, strings.Join(roots, ", "))
+}
+
+func executable(command string) (string, error) {
+	args, err := shellwords.NewParser().Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse script '%s': %v", command, err)
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], nil
+}
+
+var urlScheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+)://`)
+
+// isUrl reports whether a path starts with a scheme other than file, like ffmpeg a single letter
+// is taken as windows drive
+func isUrl(path string) bool {
+	m := urlScheme.FindStringSubmatch(path)
+	return m != nil && !strings.EqualFold(m[1], "file")
+}
+
+// localPath strips the file protocol of a path
+func localPath(path string) string {
+	if len(path) > 5 && strings.EqualFold(path[:5], "file:") {
+		return path[5:]
+	}
+	return path
+}
+
+// realPath returns the absolute path with symlinks resolved, for paths that do not exist yet
+// the symlinks of the nearest existing parent are resolved
+func realPath(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+func within(path string, root string) bool {
+	if path == root {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+func TestSandbox(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out")
+	scripts := filepath.Join(dir, "scripts")
+	for _, d := range []string{in, out, scripts} {
+		os.MkdirAll(d, os.ModePerm)
+	}
+	os.WriteFile(filepath.Join(scripts, "notify.sh"), []byte("#!/bin/sh\n"), 0755)
+	os.Symlink("/etc", filepath.Join(in, "escape"))
+
+	viper.Set("inputRoots", []string{in})
+	viper.Set("outputRoots", []string{out})
+	viper.Set("scriptDirs", []string{scripts})
+	config.Init()
+	defer func() {
+		viper.Set("inputRoots", []string{})
+		viper.Set("outputRoots", []string{})
+		viper.Set("scriptDirs", []string{})
+		config.Init()
+	}()
+
+	tests := []struct {
+		name  string
+		check func(string) error
+		path  string
+		valid bool
+	}{
+		{name: "Input inside root", check: CheckInput, path: filepath.Join(in, "movie.mov"), valid: true},
+		{name: "Input is root", check: CheckInput, path: in, valid: true},
+		{name: "Input outside root", check: CheckInput, path: "/etc/passwd", valid: false},
+		{name: "Input with traversal", check: CheckInput, path: filepath.Join(in, "..", "out", "movie.mov"), valid: false},
+		{name: "Input with similar prefix", check: CheckInput, path: in + "put/movie.mov", valid: false},
+		{name: "Input through symlink", check: CheckInput, path: filepath.Join(in, "escape", "passwd"), valid: false},
+		{name: "Input url", check: CheckInput, path: "https://example.com/movie.mov", valid: true},
+		{name: "Input with scheme in path", check: CheckInput, path: "/etc/x://y", valid: false},
+		{name: "Input with relative scheme in path", check: CheckInput, path: "../../etc/http://passwd", valid: false},
+		{name: "Input file protocol", check: CheckInput, path: "file:///etc/passwd", valid: false},
+		{name: "Input file protocol inside root", check: CheckInput, path: "file:" + filepath.Join(in, "movie.mov"), valid: true},
+		{name: "Output inside root", check: CheckOutput, path: filepath.Join(out, "new", "movie.mp4"), valid: true},
+		{name: "Output in input root", check: CheckOutput, path: filepath.Join(in, "movie.mp4"), valid: false},
+		{name: "Sidecar outside root", check: CheckSidecar, path: "/tmp/sidecar.json", valid: false},
+		{name: "Sidecar url", check: CheckSidecar, path: "https://example.com/sidecar.json", valid: false},
+		{name: "Sidecar wildcard url", check: ValidateSidecar, path: "https://example.com/${INPUT_FILE_BASENAME}.json", valid: false},
+		{name: "Script inside dir", check: CheckScript, path: filepath.Join(scripts, "notify.sh") + " ${INPUT_FILE}", valid: true},
+		{name: "Script from path", check: CheckScript, path: "sh -c 'rm -rf /'", valid: false},
+		{name: "Script with scheme in path", check: CheckScript, path: "/bin/sh://x", valid: false},
+		{name: "Script url", check: ValidateScript, path: "http://example.com/notify.sh", valid: false},
+		{name: "Wildcard inside root", check: ValidateOutput, path: out + "/${INPUT_FILE_BASENAME}.mp4", valid: true},
+		{name: "Wildcard in parent of root", check: ValidateOutput, path: dir + "/${DATE_YEAR}/out.mp4", valid: true},
+		{name: "Wildcard outside root", check: ValidateOutput, path: "/etc/${INPUT_FILE_BASENAME}", valid: false},
+		{name: "Leading wildcard", check: ValidateOutput, path: "${INPUT_FILE_DIR}/out.mp4", valid: true},
+		{name: "Wildcard script outside dir", check: ValidateScript, path: "/usr/bin/${SCRIPT}", valid: false},
+		{name: "Upload glob inside root", check: ValidateUpload, path: out + "/*.mp4", valid: true},
+		{name: "Upload glob outside root", check: ValidateUpload, path: "/etc/*", valid: false},
+		{name: "Upload match outside root", check: CheckUpload, path: filepath.Join(in, "movie.mov"), valid: false},
+		{name: "Watchfolder outside root", check: ValidateWatchfolder, path: out, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(tt.path)
+			if tt.valid && err != nil {
+				t.Errorf("Expected '%s' to be allowed, got %v", tt.path, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected '%s' to be rejected", tt.path)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		viper.Set("inputRoots", []string{})
+		config.Init()
+		if err := CheckInput("/etc/passwd"); err != nil {
+			t.Errorf("Expected no restriction without roots, got %v", err)
+		}
+	})
+}
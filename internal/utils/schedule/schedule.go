@@ -0,0 +1,166 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Validate checks the cron expression, the windows and the timezone of a schedule
+func Validate(s *dto.WatchfolderSchedule) error {
+	if s == nil {
+		return nil
+	}
+	if s.Cron == "" && len(s.Windows) == 0 {
+		return errors.New("schedule requires a cron expression or at least one window")
+	}
+	if _, err := location(s); err != nil {
+		return err
+	}
+	if s.Cron != "" {
+		if _, err := parser.Parse(s.Cron); err != nil {
+			return fmt.Errorf("invalid schedule cron expression '%s': %v", s.Cron, err)
+		}
+	}
+	for _, window := range s.Windows {
+		if _, err := parseClock(window.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return err
+		}
+		for _, day := range window.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("invalid schedule window day '%s' (expected mon, tue, wed, thu, fri, sat or sun)", day)
+			}
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether t lies within the schedule. A nil schedule is always active.
+// A cron expression marks every minute it matches as active.
+func IsActive(s *dto.WatchfolderSchedule, t time.Time) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	loc, err := location(s)
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+
+	if s.Cron != "" {
+		sched, err := parser.Parse(s.Cron)
+		if err != nil {
+			return false, err
+		}
+		minute := t.Truncate(time.Minute)
+		if sched.Next(minute.Add(-time.Second)).Equal(minute) {
+			return true, nil
+		}
+	}
+
+	for _, window := range s.Windows {
+		// a window may have started the day before and span midnight
+		for _, offset := range []int{-1, 0} {
+			start, end, ok := windowOn(window, t.AddDate(0, 0, offset))
+			if ok && !t.Before(start) && t.Before(end) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// NextActive returns t if the schedule is active at t, otherwise the time it becomes active next
+func NextActive(s *dto.WatchfolderSchedule, t time.Time) (time.Time, error) {
+	active, err := IsActive(s, t)
+	if err != nil || active {
+		return t, err
+	}
+	loc, _ := location(s)
+	t = t.In(loc)
+
+	var next time.Time
+	if s.Cron != "" {
+		sched, _ := parser.Parse(s.Cron)
+		next = sched.Next(t)
+	}
+	for _, window := range s.Windows {
+		for offset := 0; offset <= 7; offset++ {
+			start, _, ok := windowOn(window, t.AddDate(0, 0, offset))
+			if ok && start.After(t) {
+				if next.IsZero() || start.Before(next) {
+					next = start
+				}
+				break
+			}
+		}
+	}
+	if next.IsZero() {
+		return next, errors.New("schedule never becomes active")
+	}
+	return next, nil
+}
+
+// windowOn returns start and end of a window starting on the day of t
+func windowOn(window dto.WatchfolderScheduleWindow, t time.Time) (time.Time, time.Time, bool) {
+	if len(window.Days) > 0 {
+		matches := false
+		for _, day := range window.Days {
+			if weekdays[strings.ToLower(day)] == t.Weekday() {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	startClock, _ := parseClock(window.Start)
+	endClock, _ := parseClock(window.End)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	start := day.Add(startClock)
+	end := day.Add(endClock)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
+
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule window time '%s' (expected HH:MM)", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func location(s *dto.WatchfolderSchedule) (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule timezone '%s': %v", s.Timezone, err)
+	}
+	return loc, nil
+}
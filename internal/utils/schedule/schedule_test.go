@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestIsActive(t *testing.T) {
+	offHours := &dto.WatchfolderSchedule{
+		Windows: []dto.WatchfolderScheduleWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "18:00", End: "07:00"},
+			{Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00"},
+		},
+		Timezone: "Europe/Berlin",
+	}
+	nightlyCron := &dto.WatchfolderSchedule{Cron: "* 0-5 * * *", Timezone: "UTC"}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		schedule *dto.WatchfolderSchedule
+		time     time.Time
+		want     bool
+	}{
+		{name: "No schedule", schedule: nil, time: time.Now(), want: true},
+		{name: "Business hours", schedule: offHours, time: time.Date(2025, 3, 12, 10, 0, 0, 0, berlin), want: false},
+		{name: "Weekday evening", schedule: offHours, time: time.Date(2025, 3, 12, 19, 0, 0, 0, berlin), want: true},
+		{name: "Weekday night after midnight", schedule: offHours, time: time.Date(2025, 3, 13, 3, 0, 0, 0, berlin), want: true},
+		{name: "Monday morning after weekday window", schedule: offHours, time: time.Date(2025, 3, 10, 8, 0, 0, 0, berlin), want: false},
+		{name: "Weekend", schedule: offHours, time: time.Date(2025, 3, 15, 12, 0, 0, 0, berlin), want: true},
+		{name: "Timezone conversion", schedule: offHours, time: time.Date(2025, 3, 12, 17, 30, 0, 0, time.UTC), want: true},
+		{name: "Cron inside", schedule: nightlyCron, time: time.Date(2025, 3, 12, 4, 59, 30, 0, time.UTC), want: true},
+		{name: "Cron outside", schedule: nightlyCron, time: time.Date(2025, 3, 12, 6, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsActive(tt.schedule, tt.time)
+			if err != nil {
+				t.Fatalf("IsActive() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextActive(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	s := &dto.WatchfolderSchedule{
+		Windows:  []dto.WatchfolderScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "18:00", End: "07:00"}},
+		Timezone: "Europe/Berlin",
+	}
+
+	next, err := NextActive(s, time.Date(2025, 3, 12, 10, 0, 0, 0, berlin))
+	if err != nil {
+		t.Fatalf("NextActive() returned error: %v", err)
+	}
+	if want := time.Date(2025, 3, 12, 18, 0, 0, 0, berlin); !next.Equal(want) {
+		t.Errorf("NextActive() = %v, want %v", next, want)
+	}
+
+	// saturday morning after the friday window closed, next is monday evening
+	next, _ = NextActive(s, time.Date(2025, 3, 15, 8, 0, 0, 0, berlin))
+	if want := time.Date(2025, 3, 17, 18, 0, 0, 0, berlin); !next.Equal(want) {
+		t.Errorf("NextActive() = %v, want %v", next, want)
+	}
+
+	cron := &dto.WatchfolderSchedule{Cron: "* 0-5 * * *", Timezone: "UTC"}
+	next, _ = NextActive(cron, time.Date(2025, 3, 12, 6, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("NextActive() = %v, want %v", next, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *dto.WatchfolderSchedule
+		wantErr  bool
+	}{
+		{name: "Valid cron", schedule: &dto.WatchfolderSchedule{Cron: "* 20-23 * * 1-5"}},
+		{name: "Invalid cron", schedule: &dto.WatchfolderSchedule{Cron: "every night"}, wantErr: true},
+		{name: "Invalid timezone", schedule: &dto.WatchfolderSchedule{Cron: "* * * * *", Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "Invalid day", schedule: &dto.WatchfolderSchedule{Windows: []dto.WatchfolderScheduleWindow{{Days: []string{"monday"}, Start: "10:00", End: "12:00"}}}, wantErr: true},
+		{name: "Invalid time", schedule: &dto.WatchfolderSchedule{Windows: []dto.WatchfolderScheduleWindow{{Start: "25:00", End: "12:00"}}}, wantErr: true},
+		{name: "Empty", schedule: &dto.WatchfolderSchedule{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.schedule); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package wildcards
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(v any, args []any) (any, error)
+}
+
+func (f function) arity() string {
+	switch {
+	case f.maxArgs == 0:
+		return "no arguments"
+	case f.minArgs == f.maxArgs:
+		return fmt.Sprintf("%d argument(s)", f.minArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", f.minArgs, f.maxArgs)
+	}
+}
+
+var slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// functions available in wildcard pipelines (${value | function arguments...})
+var functions = map[string]function{
+	"default": {1, 1, func(v any, args []any) (any, error) {
+		if v == nil || toString(v) == "" {
+			return args[0], nil
+		}
+		return v, nil
+	}},
+	"upper": {0, 0, stringFunction(strings.ToUpper)},
+	"lower": {0, 0, stringFunction(strings.ToLower)},
+	"trim":  {0, 0, stringFunction(strings.TrimSpace)},
+	"slug": {0, 0, stringFunction(func(s string) string {
+		// strip accents (é -> e) before replacing everything else with dashes
+		var b strings.Builder
+		for _, r := range norm.NFD.String(strings.ToLower(s)) {
+			if !unicode.Is(unicode.Mn, r) {
+				b.WriteRune(r)
+			}
+		}
+		return strings.Trim(slugInvalid.ReplaceAllString(b.String(), "-"), "-")
+	})},
+	"basename": {0, 0, stringFunction(filepath.Base)},
+	"dirname":  {0, 0, stringFunction(filepath.Dir)},
+	"ext":      {0, 0, stringFunction(filepath.Ext)},
+	"stem": {0, 0, stringFunction(func(s string) string {
+		return strings.TrimSuffix(filepath.Base(s), filepath.Ext(s))
+	})},
+	"quote": {0, 0, func(v any, args []any) (any, error) {
+		return quoted(toString(v)), nil
+	}},
+	"replace": {2, 2, func(v any, args []any) (any, error) {
+		return strings.ReplaceAll(toString(v), toString(args[0]), toString(args[1])), nil
+	}},
+	"truncate": {1, 1, func(v any, args []any) (any, error) {
+		n, err := intArgument(args[0])
+		if err != nil {
+			return nil, err
+		}
+		r := []rune(toString(v))
+		if len(r) > n {
+			r = r[:n]
+		}
+		return string(r), nil
+	}},
+	"pad": {1, 1, func(v any, args []any) (any, error) {
+		n, err := intArgument(args[0])
+		if err != nil {
+			return nil, err
+		}
+		s := toString(v)
+		if len(s) < n {
+			s = strings.Repeat("0", n-len(s)) + s
+		}
+		return s, nil
+	}},
+	"round": {0, 1, func(v any, args []any) (any, error) {
+		f, err := numberInput(v)
+		if err != nil {
+			return nil, err
+		}
+		places := 0
+		if len(args) == 1 {
+			if places, err = intArgument(args[0]); err != nil {
+				return nil, err
+			}
+		}
+		p := math.Pow(10, float64(places))
+		return math.Round(f*p) / p, nil
+	}},
+	"floor": {0, 0, numberFunction(math.Floor)},
+	"ceil":  {0, 0, numberFunction(math.Ceil)},
+	"printf": {1, 1, func(v any, args []any) (any, error) {
+		format := toString(args[0])
+		if format == "" {
+			return nil, errors.New("printf requires a format")
+		}
+		if f, ok := toNumber(v); ok {
+			// integer verbs need an integer value
+			if strings.ContainsAny(format[len(format)-1:], "dxXob") {
+				return fmt.Sprintf(format, int64(f)), nil
+			}
+			return fmt.Sprintf(format, f), nil
+		}
+		return fmt.Sprintf(format, toString(v)), nil
+	}},
+}
+
+func stringFunction(fn func(string) string) func(v any, args []any) (any, error) {
+	return func(v any, args []any) (any, error) {
+		return fn(toString(v)), nil
+	}
+}
+
+func numberFunction(fn func(float64) float64) func(v any, args []any) (any, error) {
+	return func(v any, args []any) (any, error) {
+		f, err := numberInput(v)
+		if err != nil {
+			return nil, err
+		}
+		return fn(f), nil
+	}
+}
+
+func numberInput(v any) (float64, error) {
+	f, ok := toNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("'%s' is not a number", toString(v))
+	}
+	return f, nil
+}
+
+// maxIntArgument bounds lengths like those of pad and truncate, so templates cannot allocate huge strings
+const maxIntArgument = 1024
+
+func intArgument(v any) (int, error) {
+	f, ok := toNumber(v)
+	if !ok || f < 0 {
+		return 0, fmt.Errorf("'%s' is not a positive number", toString(v))
+	}
+	if f > maxIntArgument {
+		return 0, fmt.Errorf("'%s' is greater than %d", toString(v), maxIntArgument)
+	}
+	return int(f), nil
+}
@@ -0,0 +1,480 @@
+package wildcards
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Template is a parsed string with wildcards like ${INPUT_FILE_BASENAME | upper}. Expressions consist of
+// variables, string and number literals, arithmetic (+ - * / %) and a pipeline of functions. A literal
+// "${" is written as "$${".
+type Template struct {
+	segments []segment
+}
+
+type segment struct {
+	text string
+	expr node // nil for literal text
+}
+
+// Parse parses a template, unknown variables and functions as well as syntax errors are reported right away
+func Parse(raw string) (*Template, error) {
+	t := &Template{}
+	var text strings.Builder
+	for i := 0; i < len(raw); {
+		if strings.HasPrefix(raw[i:], "$${") {
+			text.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(raw[i:], "${") {
+			text.WriteByte(raw[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(raw, i+2)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated wildcard at position %d in '%s'", i, raw)
+		}
+		source := raw[i : end+1]
+		expr, err := parseExpression(raw[i+2 : end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid wildcard '%s': %v", source, err)
+		}
+		if text.Len() > 0 {
+			t.segments = append(t.segments, segment{text: text.String()})
+			text.Reset()
+		}
+		t.segments = append(t.segments, segment{text: source, expr: expr})
+		i = end + 1
+	}
+	if text.Len() > 0 {
+		t.segments = append(t.segments, segment{text: text.String()})
+	}
+	return t, nil
+}
+
+// Execute resolves all wildcards of the template, values missing without a default are reported as error
+func (t *Template) Execute(ctx *Context) (string, error) {
+	var out strings.Builder
+	for _, s := range t.segments {
+		if s.expr == nil {
+			out.WriteString(s.text)
+			continue
+		}
+		v, err := s.expr.eval(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve wildcard '%s': %v", s.text, err)
+		}
+		if v == nil {
+			return "", fmt.Errorf("wildcard '%s' has no value, use '| default' for optional values", s.text)
+		}
+		if q, ok := v.(quoted); ok {
+			out.WriteString(fmt.Sprintf("\"%s\"", string(q)))
+			continue
+		}
+		out.WriteString(toString(v))
+	}
+	return out.String(), nil
+}
+
+// Variables returns the names of all variables the template references (e.g. "metadata.show")
+func (t *Template) Variables() []string {
+	names := []string{}
+	for _, s := range t.segments {
+		if s.expr != nil {
+			names = appendVariables(names, s.expr)
+		}
+	}
+	return names
+}
+
+func appendVariables(names []string, n node) []string {
+	switch n := n.(type) {
+	case *variableNode:
+		return append(names, n.name)
+	case *binaryNode:
+		return appendVariables(appendVariables(names, n.left), n.right)
+	case *callNode:
+		names = appendVariables(names, n.input)
+		for _, arg := range n.args {
+			names = appendVariables(names, arg)
+		}
+	}
+	return names
+}
+
+// Mask replaces every wildcard with a placeholder so a template can be split into arguments before it is resolved
+func Mask(raw string) string {
+	var out strings.Builder
+	for i := 0; i < len(raw); {
+		if strings.HasPrefix(raw[i:], "$${") {
+			out.WriteString("$${")
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(raw[i:], "${") {
+			if end := closingBrace(raw, i+2); end >= 0 {
+				out.WriteString("${_}")
+				i = end + 1
+				continue
+			}
+		}
+		out.WriteByte(raw[i])
+		i++
+	}
+	return out.String()
+}
+
+// closingBrace returns the index of the brace closing a wildcard, braces inside string literals are skipped
+func closingBrace(raw string, start int) int {
+	var quote byte
+	for i := start; i < len(raw); i++ {
+		switch {
+		case quote != 0 && raw[i] == '\\':
+			i++
+		case quote != 0 && raw[i] == quote:
+			quote = 0
+		case quote != 0:
+		case raw[i] == '"' || raw[i] == '\'':
+			quote = raw[i]
+		case raw[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"' || c == '\'':
+			var s strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				s.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, token{tokenString, s.String()})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, expr[i:j]})
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(expr) && (isIdentChar(expr[j]) || expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, expr[i:j]})
+			i = j
+		case strings.IndexByte("+-*/%()|", c) >= 0:
+			tokens = append(tokens, token{tokenOperator, string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", c)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd}), nil
+}
+
+func isIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseExpression(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.pipeline()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected '%s'", p.peek().value)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && strings.Contains(ops, t.value)
+}
+
+// pipeline := sum ('|' function argument*)*
+func (p *parser) pipeline() (node, error) {
+	n, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("|") {
+		p.next()
+		name := p.next()
+		if name.kind != tokenIdent {
+			return nil, fmt.Errorf("expected function name after '|'")
+		}
+		fn, ok := functions[name.value]
+		if !ok {
+			return nil, fmt.Errorf("unknown function '%s'", name.value)
+		}
+		call := &callNode{name: name.value, fn: fn, input: n}
+		for p.peek().kind != tokenEnd && !p.isOperator("|)") {
+			arg, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+		}
+		if len(call.args) < fn.minArgs || len(call.args) > fn.maxArgs {
+			return nil, fmt.Errorf("function '%s' takes %s", name.value, fn.arity())
+		}
+		n = call
+	}
+	return n, nil
+}
+
+// sum := product (('+' | '-') product)*
+func (p *parser) sum() (node, error) {
+	n, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+-") {
+		op := p.next().value
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		n = &binaryNode{op: op[0], left: n, right: right}
+	}
+	return n, nil
+}
+
+// product := unary (('*' | '/' | '%') unary)*
+func (p *parser) product() (node, error) {
+	n, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*/%") {
+		op := p.next().value
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		n = &binaryNode{op: op[0], left: n, right: right}
+	}
+	return n, nil
+}
+
+// unary := '-' unary | number | string | variable | '(' pipeline ')'
+func (p *parser) unary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", t.value)
+		}
+		return &literalNode{value: f}, nil
+	case tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		return newVariableNode(t.value)
+	case tokenOperator:
+		switch t.value {
+		case "-":
+			n, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op: '-', left: &literalNode{value: float64(0)}, right: n}, nil
+		case "(":
+			n, err := p.pipeline()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOperator(")") {
+				return nil, fmt.Errorf("missing ')'")
+			}
+			p.next()
+			return n, nil
+		}
+		return nil, fmt.Errorf("unexpected '%s'", t.value)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
+
+type node interface {
+	eval(ctx *Context) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(ctx *Context) (any, error) {
+	return n.value, nil
+}
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n *binaryNode) eval(ctx *Context) (any, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil || left == nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil || right == nil {
+		return nil, err
+	}
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		// strings are concatenated, every other operator requires numbers
+		if n.op == '+' {
+			return toString(left) + toString(right), nil
+		}
+		return nil, fmt.Errorf("operator '%c' requires numbers, got '%s' and '%s'", n.op, toString(left), toString(right))
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	default:
+		if int64(r) == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return float64(int64(l) % int64(r)), nil
+	}
+}
+
+type callNode struct {
+	name  string
+	fn    function
+	input node
+	args  []node
+}
+
+func (n *callNode) eval(ctx *Context) (any, error) {
+	input, err := n.input.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		if args[i], err = arg.eval(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// only default deals with missing values, every other function passes them on
+	if input == nil && n.name != "default" {
+		return nil, nil
+	}
+	v, err := n.fn.call(input, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", n.name, err)
+	}
+	return v, nil
+}
+
+// quoted is a value rendered within double quotes unless it is changed by a function (e.g. ${INPUT_FILE})
+type quoted string
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case quoted:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		// maps and lists (e.g. ${metadata}) are rendered as json
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func toNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case quoted:
+		return toNumber(string(v))
+	}
+	return 0, false
+}
@@ -0,0 +1,212 @@
+package wildcards
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Context holds everything wildcards of a task resolve from, templates are parsed once per context.
+// Dates, timestamps and ${UUID} are frozen on first use, so all wildcards of a task resolve to the same values.
+type Context struct {
+	InputFile  string
+	OutputFile string
+	Source     string
+	Metadata   map[string]any
+	Params     map[string]any
+	TaskUuid   string
+	BatchUuid  string
+
+	// Now is the time dates and timestamps resolve from, the time of the first use if not set
+	Now time.Time
+
+	// Probe returns the ffprobe output of the input file, it is only called if a template uses ${probe.*}
+	Probe func() (map[string]any, error)
+
+	templates map[string]*Template
+	uuid      string
+	probe     map[string]any
+	probeErr  error
+	probed    bool
+}
+
+// Render resolves all wildcards of raw, the parsed template is cached for further calls
+func (c *Context) Render(raw string) (string, error) {
+	if c.templates == nil {
+		c.templates = map[string]*Template{}
+	}
+	t, ok := c.templates[raw]
+	if !ok {
+		var err error
+		if t, err = Parse(raw); err != nil {
+			return "", err
+		}
+		c.templates[raw] = t
+	}
+	return t.Execute(c)
+}
+
+func (c *Context) now() time.Time {
+	if c.Now.IsZero() {
+		c.Now = time.Now()
+	}
+	return c.Now
+}
+
+func (c *Context) randomUuid() string {
+	if c.uuid == "" {
+		c.uuid = uuid.NewString()
+	}
+	return c.uuid
+}
+
+func (c *Context) probeResult() (map[string]any, error) {
+	if !c.probed {
+		c.probed = true
+		if c.Probe == nil {
+			c.probeErr = fmt.Errorf("probing is not available")
+		} else {
+			c.probe, c.probeErr = c.Probe()
+		}
+	}
+	return c.probe, c.probeErr
+}
+
+// variables resolve from the context, evaluated on every execution
+var variables = map[string]func(c *Context) any{
+	"INPUT_FILE":            func(c *Context) any { return quoted(c.InputFile) },
+	"OUTPUT_FILE":           func(c *Context) any { return quoted(c.OutputFile) },
+	"INPUT_FILE_BASE":       func(c *Context) any { return filepath.Base(c.InputFile) },
+	"OUTPUT_FILE_BASE":      func(c *Context) any { return filepath.Base(c.OutputFile) },
+	"INPUT_FILE_EXTENSION":  func(c *Context) any { return filepath.Ext(filepath.Base(c.InputFile)) },
+	"OUTPUT_FILE_EXTENSION": func(c *Context) any { return filepath.Ext(filepath.Base(c.OutputFile)) },
+	"INPUT_FILE_BASENAME":   func(c *Context) any { return stem(c.InputFile) },
+	"OUTPUT_FILE_BASENAME":  func(c *Context) any { return stem(c.OutputFile) },
+	"INPUT_FILE_DIR":        func(c *Context) any { return filepath.Dir(c.InputFile) },
+	"OUTPUT_FILE_DIR":       func(c *Context) any { return filepath.Dir(c.OutputFile) },
+
+	"DATE_YEAR":      func(c *Context) any { return c.now().Format("2006") },
+	"DATE_SHORTYEAR": func(c *Context) any { return c.now().Format("06") },
+	"DATE_MONTH":     func(c *Context) any { return c.now().Format("01") },
+	"DATE_DAY":       func(c *Context) any { return c.now().Format("02") },
+	"DATE_WEEK": func(c *Context) any {
+		_, week := c.now().ISOWeek()
+		return strconv.Itoa(week)
+	},
+
+	"TIME_HOUR":   func(c *Context) any { return c.now().Format("15") },
+	"TIME_MINUTE": func(c *Context) any { return c.now().Format("04") },
+	"TIME_SECOND": func(c *Context) any { return c.now().Format("05") },
+
+	"TIMESTAMP_SECONDS":      func(c *Context) any { return strconv.FormatInt(c.now().Unix(), 10) },
+	"TIMESTAMP_MILLISECONDS": func(c *Context) any { return strconv.FormatInt(c.now().UnixMilli(), 10) },
+	"TIMESTAMP_MICROSECONDS": func(c *Context) any { return strconv.FormatInt(c.now().UnixMicro(), 10) },
+	"TIMESTAMP_NANOSECONDS":  func(c *Context) any { return strconv.FormatInt(c.now().UnixNano(), 10) },
+
+	"OS_NAME": func(c *Context) any { return runtime.GOOS },
+	"OS_ARCH": func(c *Context) any { return runtime.GOARCH },
+
+	"SOURCE": func(c *Context) any { return c.Source },
+
+	// a random uuid, stable for all wildcards of a task
+	"UUID":      func(c *Context) any { return c.randomUuid() },
+	"TASK_UUID": func(c *Context) any { return optional(c.TaskUuid) },
+	// tasks not submitted as part of a batch have no batch uuid
+	"BATCH_UUID": func(c *Context) any { return optional(c.BatchUuid) },
+}
+
+// namespaces resolve nested values by a path (e.g. ${metadata.show.title}), missing values resolve to nil
+var namespaces = map[string]func(c *Context) (any, error){
+	"metadata": func(c *Context) (any, error) {
+		if c.Metadata == nil {
+			return nil, nil
+		}
+		return c.Metadata, nil
+	},
+	"param": func(c *Context) (any, error) {
+		if c.Params == nil {
+			return nil, nil
+		}
+		return c.Params, nil
+	},
+	"probe": func(c *Context) (any, error) {
+		p, err := c.probeResult()
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe input file: %v", err)
+		}
+		return p, nil
+	},
+}
+
+type variableNode struct {
+	name      string
+	variable  func(c *Context) any
+	namespace func(c *Context) (any, error)
+	path      []string
+}
+
+func newVariableNode(name string) (node, error) {
+	if v, ok := variables[name]; ok {
+		return &variableNode{name: name, variable: v}, nil
+	}
+	parts := strings.Split(name, ".")
+	if ns, ok := namespaces[parts[0]]; ok {
+		return &variableNode{name: name, namespace: ns, path: parts[1:]}, nil
+	}
+	return nil, fmt.Errorf("unknown variable '%s'", name)
+}
+
+func (n *variableNode) eval(ctx *Context) (any, error) {
+	if n.variable != nil {
+		return n.variable(ctx), nil
+	}
+	v, err := n.namespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range n.path {
+		switch m := v.(type) {
+		case map[string]any:
+			v = m[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(m) {
+				return nil, nil
+			}
+			v = m[i]
+		default:
+			return nil, nil
+		}
+	}
+	return normalize(v), nil
+}
+
+// normalize converts json numbers so they can be used in arithmetic
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return v
+}
+
+// optional returns nil for empty values, so they are reported as missing
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func stem(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(filepath.Base(path)))
+}
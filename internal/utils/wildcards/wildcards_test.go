@@ -1,20 +1,22 @@
 package wildcards
 
 import (
+	"errors"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestReplace(t *testing.T) {
+func TestRender(t *testing.T) {
 	tests := []struct {
-		name        string
-		input       string
-		inputFile   string
-		outputFile  string
-		source      string
-		escapePaths bool
-		want        string
-		wantWin     string
+		name       string
+		input      string
+		inputFile  string
+		outputFile string
+		source     string
+		want       string
+		wantWin    string
 	}{
 		{
 			name:       "File paths with spaces",
@@ -33,6 +35,15 @@ func TestReplace(t *testing.T) {
 			want:       "input.mp4 .mp4 input /path/to",
 			wantWin:    "input.mp4 .mp4 input \\path\\to",
 		},
+		{
+			name:       "Output file components",
+			input:      "${OUTPUT_FILE_BASE} ${OUTPUT_FILE_DIR}",
+			inputFile:  "/in/input.mp4",
+			outputFile: "/out/output.mp4",
+			source:     "test",
+			want:       "output.mp4 /out",
+			wantWin:    "output.mp4 \\out",
+		},
 		{
 			name:       "System info",
 			input:      "OS: ${OS_NAME} ${OS_ARCH}",
@@ -41,11 +52,31 @@ func TestReplace(t *testing.T) {
 			source:     "test",
 			want:       "OS: " + runtime.GOOS + " " + runtime.GOARCH,
 		},
+		{
+			name:       "Functions",
+			input:      "${INPUT_FILE_BASENAME | upper}-${SOURCE | replace 'watch' 'wf'}-${INPUT_FILE | stem}",
+			inputFile:  "/path/to/input.mp4",
+			outputFile: "out.mp4",
+			source:     "watchfolder",
+			want:       "INPUT-wffolder-input",
+		},
+		{
+			name:       "Escaped wildcard",
+			input:      "$${INPUT_FILE} ${ SOURCE }",
+			inputFile:  "test.mp4",
+			outputFile: "out.mp4",
+			source:     "api",
+			want:       "${INPUT_FILE} api",
+		},
 	}
 
 	for index, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := Replace(tt.input, tt.inputFile, tt.outputFile, tt.source)
+			ctx := &Context{InputFile: tt.inputFile, OutputFile: tt.outputFile, Source: tt.source}
+			got, err := ctx.Render(tt.input)
+			if err != nil {
+				t.Fatalf("Render() failed: %v", err)
+			}
 			var want = tt.want
 			if runtime.GOOS == "windows" {
 				if tt.wantWin != "" {
@@ -53,8 +84,130 @@ func TestReplace(t *testing.T) {
 				}
 			}
 			if got != want {
-				t.Errorf("Replace() = %v, want %v (index: %d)", got, tt.want, index)
+				t.Errorf("Render() = %v, want %v (index: %d)", got, tt.want, index)
 			}
 		})
 	}
 }
+
+func TestExpressions(t *testing.T) {
+	ctx := &Context{
+		InputFile: "/in/show.mov",
+		Metadata: map[string]any{
+			"show":    "Café Society: Part 1",
+			"episode": float64(7),
+			"tags":    []any{"drama", "hd"},
+		},
+		Params: map[string]any{"crf": float64(23), "speed": "slow"},
+		Probe: func() (map[string]any, error) {
+			return map[string]any{"video": map[string]any{"height": float64(1080), "fps": "25"}}, nil
+		},
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "${metadata.show | slug}", want: "cafe-society-part-1"},
+		{input: "${metadata.episode + 1 | pad 3}", want: "008"},
+		{input: "${metadata.tags.1}", want: "hd"},
+		{input: "${metadata.season | default 1}", want: "1"},
+		{input: "${metadata.missing | upper | default 'none'}", want: "none"},
+		{input: "${probe.video.height / 2}", want: "540"},
+		{input: "${(probe.video.height - 80) * 2 % 7}", want: "5"},
+		{input: "${probe.video.fps * 2 | printf '%03d'}", want: "050"},
+		{input: "${10 / 3 | round 2}", want: "3.33"},
+		{input: "${metadata.show | truncate 4}_${metadata.episode}", want: "Café_7"},
+		{input: "${INPUT_FILE | basename | quote}", want: "\"show.mov\""},
+		{input: "-crf ${param.crf} -preset ${param.speed}", want: "-crf 23 -preset slow"},
+	}
+
+	for _, tt := range tests {
+		got, err := ctx.Render(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	parseErrors := map[string]string{
+		"${UNKNOWN}":                 "unknown variable 'UNKNOWN'",
+		"${SOURCE | shout}":          "unknown function 'shout'",
+		"${SOURCE | replace 'a'}":    "function 'replace' takes 2 argument(s)",
+		"${SOURCE":                   "unterminated wildcard",
+		"${metadata.show + }":        "unexpected end of expression",
+		"${metadata.show | default}": "function 'default' takes 1 argument(s)",
+	}
+	for input, want := range parseErrors {
+		if _, err := Parse(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing '%s', got %v", input, want, err)
+		}
+	}
+
+	ctx := &Context{
+		Metadata: map[string]any{"show": "news"},
+		Probe:    func() (map[string]any, error) { return nil, errors.New("no such file") },
+	}
+	renderErrors := map[string]string{
+		"${metadata.episode}":               "has no value",
+		"${metadata.show * 2}":              "requires numbers",
+		"${probe.video.height}":             "failed to probe input file: no such file",
+		"${TIMESTAMP_SECONDS | printf ''}":  "printf requires a format",
+		"${metadata.show | printf ''}":      "printf requires a format",
+		"${metadata.show | pad 2000000000}": "is greater than 1024",
+		"${metadata.show | truncate 5000}":  "is greater than 1024",
+	}
+	for input, want := range renderErrors {
+		if _, err := ctx.Render(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing '%s', got %v", input, want, err)
+		}
+	}
+}
+
+func TestFrozenContext(t *testing.T) {
+	now := time.Date(2024, 3, 9, 14, 5, 7, 0, time.UTC)
+	ctx := &Context{Now: now, TaskUuid: "task-1"}
+
+	first, err := ctx.Render("${UUID}-${TIMESTAMP_NANOSECONDS}")
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	second, _ := ctx.Render("${UUID}-${TIMESTAMP_NANOSECONDS}")
+	if first != second {
+		t.Errorf("Expected stable values within a context, got %s and %s", first, second)
+	}
+
+	got, _ := ctx.Render("${DATE_YEAR}${DATE_MONTH}${DATE_DAY}_${TIME_HOUR}${TIME_MINUTE}${TIME_SECOND}_${TASK_UUID}_${BATCH_UUID | default 'none'}")
+	if got != "20240309_140507_task-1_none" {
+		t.Errorf("Render() = %s", got)
+	}
+
+	other, _ := (&Context{}).Render("${UUID}")
+	if strings.HasPrefix(first, other) {
+		t.Error("Expected a new uuid for another context")
+	}
+}
+
+func TestMask(t *testing.T) {
+	got := Mask("-i ${INPUT_FILE} -metadata title=${metadata.show | default 'a } b'} $${HOME}")
+	if got != "-i ${_} -metadata title=${_} $${HOME}" {
+		t.Errorf("Mask() = %s", got)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tpl, err := Parse("${INPUT_FILE} ${(param.crf + 1) | printf '%d'} ${metadata.show | default SOURCE}")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	got := strings.Join(tpl.Variables(), ",")
+	if got != "INPUT_FILE,param.crf,metadata.show,SOURCE" {
+		t.Errorf("Variables() = %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package watchfolder
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/utils/schedule"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/yosev/debugo"
 )
@@ -24,6 +26,9 @@ type Watchfolder struct {
 
 var debug = debugo.New("watchfolder")
 
+// number of consecutive failed scans after which a watchfolder is considered failing
+const failingThreshold = 3
+
 type fileState struct {
 	Size     int64
 	Attempts int
@@ -71,6 +76,8 @@ func (w *Watchfolder) monitorWatchfolderUpdates() {
 func (w *Watchfolder) process(watchfolder *model.Watchfolder, ctx context.Context) {
 	fileStates := sync.Map{}
 	processedFiles := sync.Map{}
+	seenFiles := sync.Map{}
+	var scratchCleanup int64
 	debug.Debugf("initialized new watchfolder watcher (uuid: %s)", watchfolder.Uuid)
 
 	for {
@@ -80,70 +87,207 @@ func (w *Watchfolder) process(watchfolder *model.Watchfolder, ctx context.Contex
 			return
 		default:
 		}
+
+		// Skip scans outside of the schedule, an invalid schedule fails the scan
+		outside, err := outsideSchedule(watchfolder, time.Now())
+		if outside {
+			debug.Debugf("skipping watchfolder outside of its schedule (uuid: %s)", watchfolder.Uuid)
+			time.Sleep(time.Duration(watchfolder.Interval * int(time.Second)))
+			continue
+		}
+
 		debug.Debugf("processing watchfolder (uuid: %s)", watchfolder.Uuid)
 		watchfolder.LastCheck = time.Now().UnixMilli()
 		watchfolder.Error = ""
 
-		// Walk the directory
-		err := filepath.Walk(watchfolder.Path, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		switch {
+		case err == nil && watchfolder.Type == dto.WATCHFOLDER_S3:
+			w.cleanupScratch(watchfolder, &scratchCleanup)
+			err = w.scanS3(ctx, watchfolder, &fileStates, &processedFiles, &seenFiles)
+		case err == nil:
+			err = w.scanLocal(watchfolder, &fileStates, &processedFiles, &seenFiles)
+		}
 
-			// Skip directories
-			if info.IsDir() {
-				return nil
-			}
+		if err != nil {
+			watchfolder.Error = err.Error()
+			w.Sev.Logger().Errorf("scanning watchfolder failed (uuid: %s): %v", watchfolder.Uuid, err)
+		}
 
-			// Skip invisible files
-			if strings.HasPrefix(filepath.Base(path), ".") {
-				return nil
-			}
+		previousHealth := watchfolder.Health
+		updateHealth(watchfolder, err)
+		w.updateMetrics(watchfolder)
 
-			// Filter extensions
-			if filterOutExtension(watchfolder, path) {
-				return nil
-			}
+		w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
+		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+		if healthSeverity(watchfolder.Health) > healthSeverity(previousHealth) {
+			service.WatchfolderService().ReportError(watchfolder)
+		}
+		time.Sleep(time.Duration(watchfolder.Interval * int(time.Second)))
+	}
+}
 
-			// Check if the file has already been processed
-			if _, seen := processedFiles.Load(path); seen {
-				return nil
-			}
+// outsideSchedule reports whether a scan is skipped due to the schedule of the watchfolder
+func outsideSchedule(watchfolder *model.Watchfolder, now time.Time) (bool, error) {
+	active, err := schedule.IsActive(watchfolder.Schedule, now)
+	if err != nil {
+		return false, fmt.Errorf("invalid schedule: %v", err)
+	}
+	return !active, nil
+}
 
-			// Determine if the file is ready for processing
-			if shouldProcessFile(path, info, &fileStates, watchfolder.GrowthChecks) {
-				w.createTask(path, watchfolder)
-				processedFiles.Store(path, true) // Mark as processed
-				fileStates.Delete(path)          // Remove from tracking
-			}
+// scanLocal walks the watchfolder directory and creates tasks for new files
+func (w *Watchfolder) scanLocal(watchfolder *model.Watchfolder, fileStates *sync.Map, processedFiles *sync.Map, seenFiles *sync.Map) error {
+	groups := map[string]*fileGroup{}
+
+	// Walk the directory
+	err := filepath.Walk(watchfolder.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
+		// Skip directories
+		if info.IsDir() {
 			return nil
-		})
+		}
 
-		if err != nil {
-			watchfolder.Error = err.Error()
-			w.Sev.Logger().Errorf("walking watchfolder directory failed (uuid: %s): %v", watchfolder.Uuid, err)
+		// Skip invisible files
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			return nil
 		}
 
-		w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
-		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
-		time.Sleep(time.Duration(watchfolder.Interval * int(time.Second)))
+		// Filter extensions (trigger and sidecar files are never filtered)
+		if filterOutExtension(watchfolder, path) && !isTriggerFile(watchfolder, path) && !isSidecarFile(watchfolder, path) {
+			return nil
+		}
+
+		// Check if the file has already been processed
+		if _, seen := processedFiles.Load(path); seen {
+			return nil
+		}
+
+		if _, seen := seenFiles.LoadOrStore(path, true); !seen {
+			watchfolder.FilesSeen++
+		}
+
+		// Collect files by basename if the watchfolder is triggered
+		if watchfolder.Trigger != nil {
+			key := groupKey(path)
+			if _, ok := groups[key]; !ok {
+				groups[key] = &fileGroup{Name: key, Files: map[string]os.FileInfo{}}
+			}
+			groups[key].Files[path] = info
+			return nil
+		}
+
+		// Determine if the file is ready for processing
+		if shouldProcessFile(path, info.Size(), fileStates, watchfolder.GrowthChecks) {
+			w.createTask(filepath.Base(path), path, watchfolder, nil)
+			processedFiles.Store(path, true) // Mark as processed
+			fileStates.Delete(path)          // Remove from tracking
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		for _, group := range groups {
+			w.processGroup(group, watchfolder, fileStates, processedFiles)
+		}
+	}
+
+	return err
+}
+
+// processGroup creates a task for a group of files once its trigger file arrived and all files stopped growing
+func (w *Watchfolder) processGroup(group *fileGroup, watchfolder *model.Watchfolder, fileStates *sync.Map, processedFiles *sync.Map) {
+	if group.triggerPath(watchfolder) == "" {
+		return
+	}
+
+	// every file of the group needs to be evaluated so their growth checks keep progressing
+	ready := true
+	for _, path := range group.sortedPaths() {
+		if !shouldProcessFile(path, group.Files[path].Size(), fileStates, watchfolder.GrowthChecks) {
+			ready = false
+		}
+	}
+	if !ready {
+		return
+	}
+
+	// the group is kept until the input arrived and the sidecar files are readable, it is checked again with the next scan
+	input := group.inputPath(watchfolder)
+	if input == "" {
+		debug.Debugf("waiting for input file of watchfolder (uuid: %s) group: %s", watchfolder.Uuid, group.Name)
+		return
+	}
+
+	metadata, err := group.metadata(watchfolder)
+	if err != nil {
+		w.Sev.Logger().Errorf("failed to create task for watchfolder (uuid: %s) group: %s: %v", watchfolder.Uuid, group.Name, err)
+		return
+	}
+
+	w.createTask(filepath.Base(input), input, watchfolder, (*dto.InterfaceMap)(&metadata))
+	for path := range group.Files {
+		processedFiles.Store(path, true) // Mark as processed
+		fileStates.Delete(path)          // Remove from tracking
 	}
 }
 
-func (w *Watchfolder) createTask(path string, watchfolder *model.Watchfolder) {
+func (w *Watchfolder) createTask(name string, path string, watchfolder *model.Watchfolder, metadata *dto.InterfaceMap) {
 	_, err := service.TaskService().NewTask(&dto.NewTask{
-		Preset:    watchfolder.Preset,
-		Name:      filepath.Base(path),
-		InputFile: path,
+		Preset:      watchfolder.Preset,
+		Params:      watchfolder.Params,
+		Watchfolder: watchfolder.Uuid,
+		Name:        name,
+		InputFile:   path,
+		Metadata:    metadata,
 	}, "", "watchfolder")
 	if err != nil {
 		w.Sev.Logger().Errorf("failed to create task for watchfolder (uuid: %s) file: %s: %v", watchfolder.Uuid, path, err)
 		return
 	}
+	watchfolder.FilesIngested++
 	debug.Debugf("created new task for watchfolder (uuid: %s) file: %s", watchfolder.Uuid, path)
 }
 
+// updateHealth derives the health of a watchfolder from the result of its latest scan
+func updateHealth(watchfolder *model.Watchfolder, err error) {
+	if err == nil {
+		watchfolder.Health = dto.WATCHFOLDER_OK
+		watchfolder.ConsecutiveFailures = 0
+		watchfolder.LastSuccessfulScan = watchfolder.LastCheck
+		return
+	}
+
+	watchfolder.ConsecutiveFailures++
+	if watchfolder.ConsecutiveFailures >= failingThreshold {
+		watchfolder.Health = dto.WATCHFOLDER_FAILING
+	} else {
+		watchfolder.Health = dto.WATCHFOLDER_DEGRADED
+	}
+}
+
+func healthSeverity(health dto.WatchfolderHealth) float64 {
+	switch health {
+	case dto.WATCHFOLDER_DEGRADED:
+		return 1
+	case dto.WATCHFOLDER_FAILING:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func (w *Watchfolder) updateMetrics(watchfolder *model.Watchfolder) {
+	w.Sev.Metrics().GaugeVec("watchfolder.health").WithLabelValues(watchfolder.Uuid).Set(healthSeverity(watchfolder.Health))
+	w.Sev.Metrics().GaugeVec("watchfolder.consecutiveFailures").WithLabelValues(watchfolder.Uuid).Set(float64(watchfolder.ConsecutiveFailures))
+	w.Sev.Metrics().GaugeVec("watchfolder.lastSuccessfulScan").WithLabelValues(watchfolder.Uuid).Set(float64(watchfolder.LastSuccessfulScan / 1000))
+	w.Sev.Metrics().GaugeVec("watchfolder.filesSeen").WithLabelValues(watchfolder.Uuid).Set(float64(watchfolder.FilesSeen))
+	w.Sev.Metrics().GaugeVec("watchfolder.filesIngested").WithLabelValues(watchfolder.Uuid).Set(float64(watchfolder.FilesIngested))
+}
+
 func filterOutExtension(watchfolder *model.Watchfolder, path string) bool {
 	if watchfolder.Filter != nil && watchfolder.Filter.Extensions != nil {
 		if len(watchfolder.Filter.Extensions.Exclude) > 0 {
@@ -172,25 +316,25 @@ func filterOutExtension(watchfolder *model.Watchfolder, path string) bool {
 }
 
 // shouldProcessFile determines if a file is ready for processing based on growth attempts.
-func shouldProcessFile(path string, info os.FileInfo, fileStates *sync.Map, growthChecks int) bool {
+func shouldProcessFile(path string, size int64, fileStates *sync.Map, growthChecks int) bool {
 	if growthChecks == 0 {
 		// If no growth checks are required, the file is ready immediately
 		return true
 	}
 
 	// Get or initialize the file state
-	state, _ := fileStates.LoadOrStore(path, &fileState{Size: info.Size(), Attempts: 1})
+	state, _ := fileStates.LoadOrStore(path, &fileState{Size: size, Attempts: 1})
 	fileState := state.(*fileState)
 
 	// Check if the file size is stable
-	if info.Size() == fileState.Size {
+	if size == fileState.Size {
 		fileState.Attempts++
 		if fileState.Attempts >= growthChecks {
 			return true
 		}
 	} else {
 		// File size changed, reset attempts
-		fileState.Size = info.Size()
+		fileState.Size = size
 		fileState.Attempts = 1
 		fileStates.Store(path, fileState)
 	}
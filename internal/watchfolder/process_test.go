@@ -0,0 +1,141 @@
+package watchfolder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/metrics"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	err = db.AutoMigrate(&model.Task{}, &model.Preset{}, &model.PresetRevision{}, &model.Webhook{}, &model.WebhookDelivery{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	s := sev.New("test", "", "", 3000)
+	s.SetDB(db)
+	service.Init(s)
+
+	// setup metrics
+	metrics := &metrics.Metrics{}
+	for name, gauge := range metrics.Gauges() {
+		s.Metrics().RegisterGauge(name, gauge)
+	}
+	for name, gauge := range metrics.GaugesVec() {
+		s.Metrics().RegisterGaugeVec(name, gauge)
+	}
+
+	return db, s
+}
+
+func TestUpdateHealth(t *testing.T) {
+	watchfolder := &model.Watchfolder{Health: dto.WATCHFOLDER_OK, LastCheck: 1000}
+
+	expected := []dto.WatchfolderHealth{dto.WATCHFOLDER_DEGRADED, dto.WATCHFOLDER_DEGRADED, dto.WATCHFOLDER_FAILING, dto.WATCHFOLDER_FAILING}
+	for i, health := range expected {
+		updateHealth(watchfolder, errors.New("lstat /missing: no such file or directory"))
+		if watchfolder.Health != health {
+			t.Errorf("Expected health %s after %d failures, got %s", health, i+1, watchfolder.Health)
+		}
+		if watchfolder.ConsecutiveFailures != i+1 {
+			t.Errorf("Expected %d consecutive failures, got %d", i+1, watchfolder.ConsecutiveFailures)
+		}
+	}
+
+	watchfolder.LastCheck = 2000
+	updateHealth(watchfolder, nil)
+	if watchfolder.Health != dto.WATCHFOLDER_OK || watchfolder.ConsecutiveFailures != 0 {
+		t.Errorf("Expected watchfolder to recover, got %s with %d failures", watchfolder.Health, watchfolder.ConsecutiveFailures)
+	}
+	if watchfolder.LastSuccessfulScan != 2000 {
+		t.Errorf("Expected last successful scan to be 2000, got %d", watchfolder.LastSuccessfulScan)
+	}
+}
+
+func TestOutsideSchedule(t *testing.T) {
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC) // monday
+
+	tests := []struct {
+		name     string
+		schedule *dto.WatchfolderSchedule
+		outside  bool
+		err      bool
+	}{
+		{name: "No schedule"},
+		{name: "Inside window", schedule: &dto.WatchfolderSchedule{Windows: []dto.WatchfolderScheduleWindow{{Start: "08:00", End: "18:00"}}}},
+		{name: "Outside window", schedule: &dto.WatchfolderSchedule{Windows: []dto.WatchfolderScheduleWindow{{Start: "20:00", End: "06:00"}}}, outside: true},
+		{name: "Invalid timezone", schedule: &dto.WatchfolderSchedule{Timezone: "Mars/Olympus", Cron: "* * * * *"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outside, err := outsideSchedule(&model.Watchfolder{Schedule: tt.schedule}, now)
+			if outside != tt.outside || (err != nil) != tt.err {
+				t.Errorf("Expected outside=%v err=%v, got %v %v", tt.outside, tt.err, outside, err)
+			}
+		})
+	}
+}
+
+func TestProcessGroup(t *testing.T) {
+	db, s := setupTestDB(t)
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	preset, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "Group Preset", Command: "-y -i ${INPUT_FILE} ${OUTPUT_FILE}", OutputFile: "/tmp/out.mp4"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create preset: %v", err)
+	}
+	watchfolder := &model.Watchfolder{Uuid: "group-watchfolder", Preset: preset.Uuid, Trigger: &dto.WatchfolderTrigger{Extensions: []string{"xml"}}}
+	w := &Watchfolder{Sev: s}
+	fileStates := sync.Map{}
+	processedFiles := sync.Map{}
+
+	dir := t.TempDir()
+	group := &fileGroup{Name: filepath.Join(dir, "show"), Files: map[string]os.FileInfo{}}
+	add := func(name string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(name), 0644)
+		info, _ := os.Stat(path)
+		group.Files[path] = info
+		return path
+	}
+
+	t.Run("Keep trigger arriving before the input", func(t *testing.T) {
+		trigger := add("show.xml")
+		w.processGroup(group, watchfolder, &fileStates, &processedFiles)
+		if _, ok := processedFiles.Load(trigger); ok {
+			t.Error("Expected trigger file not to be marked processed without an input")
+		}
+	})
+
+	t.Run("Create task once the input arrived", func(t *testing.T) {
+		input := add("show.mp4")
+		w.processGroup(group, watchfolder, &fileStates, &processedFiles)
+		for path := range group.Files {
+			if _, ok := processedFiles.Load(path); !ok {
+				t.Errorf("Expected %s to be marked processed", path)
+			}
+		}
+		var task model.Task
+		if err := db.Where("watchfolder = ?", watchfolder.Uuid).First(&task).Error; err != nil || task.InputFile.Raw != input {
+			t.Errorf("Expected task for input %s, got %+v (%v)", input, task.InputFile, err)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package watchfolder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/storage"
+)
+
+// presigned urls are valid for the maximum duration allowed by SigV4 as tasks may stay queued for a while
+const presignExpiry = 7 * 24 * time.Hour
+
+// scanS3 lists the configured bucket prefix and creates tasks for new objects
+func (w *Watchfolder) scanS3(ctx context.Context, watchfolder *model.Watchfolder, fileStates *sync.Map, processedFiles *sync.Map, seenFiles *sync.Map) error {
+	client, err := storage.NewS3(&watchfolder.S3.S3Connection)
+	if err != nil {
+		return err
+	}
+
+	objects, err := client.List(ctx, watchfolder.S3.Prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		// Skip folder placeholders and invisible files
+		if strings.HasSuffix(object.Key, "/") || strings.HasPrefix(path.Base(object.Key), ".") {
+			continue
+		}
+
+		// Filter extensions
+		if filterOutExtension(watchfolder, object.Key) {
+			continue
+		}
+
+		// Check if the object has already been processed
+		if _, seen := processedFiles.Load(object.Key); seen {
+			continue
+		}
+
+		if _, seen := seenFiles.LoadOrStore(object.Key, true); !seen {
+			watchfolder.FilesSeen++
+		}
+
+		if !shouldProcessFile(object.Key, object.Size, fileStates, watchfolder.GrowthChecks) {
+			continue
+		}
+
+		input, err := w.fetchS3Object(ctx, client, watchfolder, object)
+		if err != nil {
+			// the object is retried with the next scan
+			w.Sev.Logger().Errorf("failed to fetch object for watchfolder (uuid: %s) key: %s: %v", watchfolder.Uuid, object.Key, err)
+			continue
+		}
+
+		metadata := dto.InterfaceMap{
+			"s3": map[string]interface{}{
+				"bucket": watchfolder.S3.Bucket,
+				"key":    object.Key,
+				"etag":   object.ETag,
+				"size":   object.Size,
+			},
+		}
+		w.createTask(path.Base(object.Key), input, watchfolder, &metadata)
+		processedFiles.Store(object.Key, true) // Mark as processed
+		fileStates.Delete(object.Key)          // Remove from tracking
+	}
+
+	return nil
+}
+
+// fetchS3Object returns the input for ffmpeg: either a local copy in the scratch directory or a presigned url
+func (w *Watchfolder) fetchS3Object(ctx context.Context, client *storage.S3, watchfolder *model.Watchfolder, object storage.Object) (string, error) {
+	if watchfolder.S3.Mode == dto.S3_PRESIGN {
+		return client.PresignedUrl(ctx, object.Key, presignExpiry)
+	}
+
+	scratchDir, err := scratchDir(watchfolder)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(scratchDir, filepath.FromSlash(object.Key))
+	if !strings.HasPrefix(target, scratchDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("object key '%s' escapes the scratch directory", object.Key)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	return target, client.Download(ctx, object.Key, target)
+}
+
+// scratchDir returns the absolute directory objects of a download mode watchfolder are stored in
+func scratchDir(watchfolder *model.Watchfolder) (string, error) {
+	dir := watchfolder.S3.ScratchDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "ffmate", watchfolder.Uuid)
+	}
+	return filepath.Abs(dir)
+}
+
+// cleanupScratch removes the downloaded objects of tasks that finished, failed, were canceled or deleted.
+// Tasks changed since the previous cleanup are checked, so the first cleanup after a start covers all earlier tasks.
+func (w *Watchfolder) cleanupScratch(watchfolder *model.Watchfolder, since *int64) {
+	if watchfolder.S3 == nil || watchfolder.S3.Mode == dto.S3_PRESIGN {
+		return
+	}
+
+	dir, err := scratchDir(watchfolder)
+	if err != nil {
+		w.Sev.Logger().Warnf("failed to clean up scratch directory of watchfolder (uuid: %s): %v", watchfolder.Uuid, err)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	tasks, err := service.TaskService().GetFinishedTasksByWatchfolder(watchfolder.Uuid, *since)
+	if err != nil {
+		w.Sev.Logger().Warnf("failed to clean up scratch directory of watchfolder (uuid: %s): %v", watchfolder.Uuid, err)
+		return
+	}
+	*since = now
+
+	for _, task := range *tasks {
+		// only files the watchfolder downloaded are removed
+		if task.InputFile == nil || !strings.HasPrefix(task.InputFile.Raw, dir+string(filepath.Separator)) {
+			continue
+		}
+		if err := os.Remove(task.InputFile.Raw); err == nil {
+			debug.Debugf("removed downloaded object of task (uuid: %s): %s", task.Uuid, task.InputFile.Raw)
+		} else if !os.IsNotExist(err) {
+			w.Sev.Logger().Warnf("failed to remove downloaded object of task (uuid: %s): %v", task.Uuid, err)
+		}
+	}
+}
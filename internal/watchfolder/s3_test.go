@@ -0,0 +1,66 @@
+package watchfolder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestCleanupScratch(t *testing.T) {
+	db, s := setupTestDB(t)
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside.mp4")
+	watchfolder := &model.Watchfolder{Uuid: "scratch-watchfolder", S3: &dto.WatchfolderS3{Mode: dto.S3_DOWNLOAD, ScratchDir: dir}}
+
+	files := map[dto.TaskStatus]string{}
+	for _, status := range []dto.TaskStatus{dto.QUEUED, dto.RUNNING, dto.DONE_SUCCESSFUL, dto.DONE_ERROR, dto.DONE_CANCELED} {
+		files[status] = filepath.Join(dir, "videos", string(status)+".mp4")
+	}
+	files["OUTSIDE"] = outside
+	for status, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if status == "OUTSIDE" {
+			status = dto.DONE_SUCCESSFUL
+		}
+		db.Create(&model.Task{Uuid: filepath.Base(file), Watchfolder: watchfolder.Uuid, Status: status, InputFile: &dto.RawResolved{Raw: file}})
+	}
+
+	deleted := filepath.Join(dir, "deleted.mp4")
+	if err := os.WriteFile(deleted, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	task := &model.Task{Uuid: "deleted", Watchfolder: watchfolder.Uuid, Status: dto.QUEUED, InputFile: &dto.RawResolved{Raw: deleted}}
+	db.Create(task)
+	db.Delete(task)
+
+	w := &Watchfolder{Sev: s}
+	var since int64
+	w.cleanupScratch(watchfolder, &since)
+
+	for status, file := range files {
+		_, err := os.Stat(file)
+		removed := os.IsNotExist(err)
+		expected := status == dto.DONE_SUCCESSFUL || status == dto.DONE_ERROR || status == dto.DONE_CANCELED
+		if removed != expected {
+			t.Errorf("Expected file of %s task to be removed: %v, got %v", status, expected, removed)
+		}
+	}
+	if _, err := os.Stat(deleted); !os.IsNotExist(err) {
+		t.Error("Expected file of deleted task to be removed")
+	}
+	if since == 0 || since > time.Now().UnixMilli() {
+		t.Errorf("Expected cleanup time to be recorded, got %d", since)
+	}
+}
@@ -0,0 +1,210 @@
+package watchfolder
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+)
+
+// fileGroup holds all files of a watchfolder that share the same basename (eg. show.mp4, show.xml, show.ready)
+type fileGroup struct {
+	Name  string
+	Files map[string]os.FileInfo
+}
+
+// groupKey returns the key a file is grouped by: its directory plus the filename up to the first dot
+func groupKey(path string) string {
+	base := filepath.Base(path)
+	if i := strings.Index(base, "."); i > 0 {
+		base = base[:i]
+	}
+	return filepath.Join(filepath.Dir(path), base)
+}
+
+func hasExtension(path string, extensions []string) bool {
+	for _, ext := range extensions {
+		if strings.HasSuffix(strings.ToLower(path), "."+strings.ToLower(strings.TrimPrefix(ext, "."))) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTriggerFile(watchfolder *model.Watchfolder, path string) bool {
+	return watchfolder.Trigger != nil && hasExtension(path, watchfolder.Trigger.Extensions)
+}
+
+func isSidecarFile(watchfolder *model.Watchfolder, path string) bool {
+	return watchfolder.Trigger != nil && hasExtension(path, watchfolder.Trigger.SidecarExtensions)
+}
+
+// triggerPath returns the path of the trigger file within the group or an empty string if it has not arrived yet
+func (g *fileGroup) triggerPath(watchfolder *model.Watchfolder) string {
+	for _, path := range g.sortedPaths() {
+		if isTriggerFile(watchfolder, path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// inputPath returns the largest file of the group that is neither a trigger nor a sidecar file
+func (g *fileGroup) inputPath(watchfolder *model.Watchfolder) string {
+	var input string
+	var size int64 = -1
+	for _, path := range g.sortedPaths() {
+		if isTriggerFile(watchfolder, path) || isSidecarFile(watchfolder, path) {
+			continue
+		}
+		if g.Files[path].Size() > size {
+			input = path
+			size = g.Files[path].Size()
+		}
+	}
+	return input
+}
+
+func (g *fileGroup) sortedPaths() []string {
+	paths := make([]string, 0, len(g.Files))
+	for path := range g.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// metadata builds the task metadata for a group including the parsed content of all sidecar files
+func (g *fileGroup) metadata(watchfolder *model.Watchfolder) (map[string]interface{}, error) {
+	paths := g.sortedPaths()
+	files := make([]interface{}, len(paths))
+	for i, path := range paths {
+		files[i] = path
+	}
+
+	metadata := map[string]interface{}{
+		"group": map[string]interface{}{
+			"name":    filepath.Base(g.Name),
+			"files":   files,
+			"trigger": g.triggerPath(watchfolder),
+		},
+	}
+
+	sidecar := map[string]interface{}{}
+	for _, path := range paths {
+		if !isSidecarFile(watchfolder, path) {
+			continue
+		}
+		content, err := parseSidecar(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sidecar file '%s': %v", path, err)
+		}
+		for k, v := range content {
+			sidecar[k] = v
+		}
+	}
+	if len(sidecar) > 0 {
+		metadata["sidecar"] = sidecar
+	}
+
+	return metadata, nil
+}
+
+// parseSidecar reads a json or xml sidecar file into a map
+func parseSidecar(path string) (map[string]interface{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var content interface{}
+		if err := json.Unmarshal(b, &content); err != nil {
+			return nil, err
+		}
+		if m, ok := content.(map[string]interface{}); ok {
+			return m, nil
+		}
+		return map[string]interface{}{"value": content}, nil
+	case ".xml":
+		return parseXml(b)
+	default:
+		return nil, fmt.Errorf("unsupported sidecar format '%s'", filepath.Ext(path))
+	}
+}
+
+// parseXml converts an xml document into a map. Attributes are prefixed with '@',
+// repeated elements become lists and text of elements with children is stored as '#text'.
+func parseXml(b []byte) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("no root element found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := parseXmlElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{start.Name.Local: value}, nil
+		}
+	}
+}
+
+func parseXmlElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	element := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		element["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := parseXmlElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			if existing, ok := element[t.Name.Local]; ok {
+				if list, ok := existing.([]interface{}); ok {
+					element[t.Name.Local] = append(list, child)
+				} else {
+					element[t.Name.Local] = []interface{}{existing, child}
+				}
+			} else {
+				element[t.Name.Local] = child
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(element) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				element["#text"] = content
+			}
+			return element, nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package watchfolder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestGroupKey(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/drop/show.mp4", want: filepath.Join("/drop", "show")},
+		{path: "/drop/show.mp4.ready", want: filepath.Join("/drop", "show")},
+		{path: "/drop/show.en.wav", want: filepath.Join("/drop", "show")},
+		{path: "/drop/sub/show.xml", want: filepath.Join("/drop/sub", "show")},
+	}
+
+	for _, tt := range tests {
+		if got := groupKey(tt.path); got != tt.want {
+			t.Errorf("groupKey(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "show.json")
+	os.WriteFile(jsonPath, []byte(`{"show": "news", "episode": 12}`), 0644)
+	xmlPath := filepath.Join(dir, "show.xml")
+	os.WriteFile(xmlPath, []byte(`<asset id="42"><title>News</title><tag>a</tag><tag>b</tag></asset>`), 0644)
+
+	t.Run("json", func(t *testing.T) {
+		got, err := parseSidecar(jsonPath)
+		if err != nil {
+			t.Fatalf("Failed to parse json sidecar: %v", err)
+		}
+		want := map[string]interface{}{"show": "news", "episode": float64(12)}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseSidecar() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		got, err := parseSidecar(xmlPath)
+		if err != nil {
+			t.Fatalf("Failed to parse xml sidecar: %v", err)
+		}
+		want := map[string]interface{}{
+			"asset": map[string]interface{}{
+				"@id":   "42",
+				"title": "News",
+				"tag":   []interface{}{"a", "b"},
+			},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseSidecar() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestFileGroup(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"show.mp4":    "videovideovideo",
+		"show.en.wav": "audio",
+		"show.json":   `{"show": "news"}`,
+	}
+	group := &fileGroup{Name: filepath.Join(dir, "show"), Files: map[string]os.FileInfo{}}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		info, _ := os.Stat(path)
+		group.Files[path] = info
+	}
+
+	watchfolder := &model.Watchfolder{Trigger: &dto.WatchfolderTrigger{Extensions: []string{"ready"}, SidecarExtensions: []string{"json"}}}
+
+	if trigger := group.triggerPath(watchfolder); trigger != "" {
+		t.Errorf("Expected no trigger file, got %s", trigger)
+	}
+
+	trigger := filepath.Join(dir, "show.ready")
+	os.WriteFile(trigger, []byte{}, 0644)
+	info, _ := os.Stat(trigger)
+	group.Files[trigger] = info
+
+	if got := group.triggerPath(watchfolder); got != trigger {
+		t.Errorf("Expected trigger file %s, got %s", trigger, got)
+	}
+
+	if got := group.inputPath(watchfolder); got != filepath.Join(dir, "show.mp4") {
+		t.Errorf("Expected largest media file as input, got %s", got)
+	}
+
+	metadata, err := group.metadata(watchfolder)
+	if err != nil {
+		t.Fatalf("Failed to build metadata: %v", err)
+	}
+	if metadata["sidecar"].(map[string]interface{})["show"] != "news" {
+		t.Errorf("Expected sidecar content in metadata, got %v", metadata["sidecar"])
+	}
+	if len(metadata["group"].(map[string]interface{})["files"].([]interface{})) != 4 {
+		t.Errorf("Expected 4 files in group metadata, got %v", metadata["group"])
+	}
+}
@@ -35,3 +35,11 @@ func HttpInvalidQuery(name string) *HttpError {
 func HttpNotFound(err error, docs string) *HttpError {
 	return &HttpError{HttpCode: 400, Code: "002.000.0008", Error: "not.found", Message: err.Error(), Docs: docs}
 }
+
+func HttpUnauthorized(err error, docs string) *HttpError {
+	return &HttpError{HttpCode: 401, Code: "002.000.0009", Error: "unauthorized", Message: err.Error(), Docs: docs}
+}
+
+func HttpForbidden(err error, docs string) *HttpError {
+	return &HttpError{HttpCode: 403, Code: "002.000.0010", Error: "forbidden", Message: err.Error(), Docs: docs}
+}
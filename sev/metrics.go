@@ -12,10 +12,11 @@ func (s *Sev) Metrics() *metrics.Metrics {
 	return s.metrics
 }
 
-func (s *Sev) registerMetrics() {
+// RegisterMetrics serves the prometheus metrics, it must be called after all middlewares have been registered
+func (s *Sev) RegisterMetrics(handlers ...gin.HandlerFunc) {
 	h := promhttp.HandlerFor(s.metrics.Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
-	s.Gin().GET("/metrics", func(c *gin.Context) {
+	s.Gin().GET("/metrics", append(handlers, func(c *gin.Context) {
 		h.ServeHTTP(c.Writer, c.Request)
-	})
+	})...)
 	debugMetrics.Debug("registered prometheus http handler")
 }
@@ -122,8 +122,6 @@ func New(name string, version string, dbPath string, port uint) *Sev {
 		ctx: context.Background(),
 	}
 
-	sev.registerMetrics()
-
 	return sev
 }
 
@@ -2,8 +2,17 @@ package sev
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/welovemedia/ffmate/internal/config"
 	"github.com/welovemedia/ffmate/internal/database/model"
@@ -15,32 +24,149 @@ type eventMessage struct {
 	Data  interface{}      `json:"data"`
 }
 
+// WebhookAttempt is the outcome of a single http request against a webhook target
+type WebhookAttempt struct {
+	Attempt  int
+	Status   int
+	Latency  time.Duration
+	Response string
+	Error    error
+}
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 5
+	webhookResponseSize = 1024
+)
+
+// first retry delay, doubled for every following attempt
+var webhookBackoff = 1 * time.Second
+
 var debugWebhook = debug.Extend("webhook")
 
-func (s *Sev) FireWebhook(webhook *model.Webhook, data interface{}) {
-	msg := eventMessage{
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseWebhookTemplate parses a payload template. The template is executed against the event envelope
+// as it would be sent without template, e.g. {{ .event }} or {{ .data.status }}.
+func ParseWebhookTemplate(body string) (*template.Template, error) {
+	return template.New("webhook").Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(body)
+}
+
+// WebhookPayload wraps data into the event envelope sent to webhook targets or renders the webhook template
+func (s *Sev) WebhookPayload(webhook *model.Webhook, data interface{}) ([]byte, error) {
+	b, err := json.Marshal(&eventMessage{
 		Event: webhook.Event,
 		Data:  data,
+	})
+	if err != nil || webhook.Template == "" {
+		return b, err
+	}
+
+	// templates work on the json representation so field names match the default payload
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		return nil, err
 	}
-	b, err := json.Marshal(&msg)
+
+	tmpl, err := ParseWebhookTemplate(webhook.Template)
 	if err != nil {
-		s.Logger().Warnf("failed to fire webhook for event '%s' (uuid: %s) due to marshalling problems: %+v", webhook.Event, webhook.Uuid, err)
-		return
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, envelope); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", webhook.Url, bytes.NewBuffer(b))
+// SignWebhook returns the signature sent in the X-FFmate-Signature header.
+//
+// Receivers verify a delivery by computing HMAC-SHA256 with the webhook secret over
+// "<X-FFmate-Timestamp>.<raw body>" and comparing it in constant time to the header value (without the "sha256=" prefix).
+// To reject replayed deliveries, receivers should discard requests whose timestamp is older than a few minutes
+// and remember the X-FFmate-Delivery ids seen within that window. Retries of a delivery are signed with a fresh
+// timestamp but keep their delivery id.
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook posts the payload to the webhook target. Network errors and 5xx responses are retried
+// with exponential backoff, onAttempt is called after each attempt.
+func (s *Sev) DeliverWebhook(webhook *model.Webhook, delivery string, payload []byte, onAttempt func(*WebhookAttempt)) error {
+	var attempt *WebhookAttempt
+	for i := 1; i <= webhookMaxAttempts; i++ {
+		attempt = s.sendWebhook(webhook, delivery, payload)
+		attempt.Attempt = i
+		if onAttempt != nil {
+			onAttempt(attempt)
+		}
+
+		if attempt.Error == nil && attempt.Status < 500 {
+			break
+		}
+		if i < webhookMaxAttempts {
+			debugWebhook.Debugf("retrying webhook for event '%s' (uuid: %s attempt: %d)", webhook.Event, webhook.Uuid, i)
+			time.Sleep(webhookBackoff << (i - 1))
+		}
+	}
+
+	if attempt.Error != nil {
+		s.Logger().Warnf("failed to fire webhook for event '%s' (uuid: %s) due to http problems: %+v", webhook.Event, webhook.Uuid, attempt.Error)
+		return attempt.Error
+	}
+	if attempt.Status >= 300 {
+		s.Logger().Warnf("failed to fire webhook for event '%s' (uuid: %s) due to unexpected status code: %d", webhook.Event, webhook.Uuid, attempt.Status)
+		return fmt.Errorf("unexpected status code %d", attempt.Status)
+	}
+
+	debugWebhook.Debugf("fired webhook for event '%s' (uuid: %s)", webhook.Event, webhook.Uuid)
+	return nil
+}
+
+func (s *Sev) sendWebhook(webhook *model.Webhook, delivery string, payload []byte) *WebhookAttempt {
+	req, err := http.NewRequest("POST", webhook.Url, bytes.NewBuffer(payload))
 	if err != nil {
-		s.Logger().Errorf("failed to create http request: %s", err)
-		return
+		return &WebhookAttempt{Error: err}
+	}
+	contentType := webhook.ContentType
+	if contentType == "" {
+		contentType = "application/json"
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Type", contentType)
 	req.Header.Add("User-Agent", config.Config().AppName+"/"+config.Config().AppVersion)
+	for k, v := range webhook.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("X-FFmate-Delivery", delivery)
+	if webhook.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set("X-FFmate-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("X-FFmate-Signature", "sha256="+SignWebhook(webhook.Secret, timestamp, payload))
+	}
 
-	_, err = client.Do(req)
+	start := time.Now()
+	res, err := webhookClient.Do(req)
+	attempt := &WebhookAttempt{Latency: time.Since(start), Error: err}
 	if err != nil {
-		s.Logger().Warnf("failed to fire webhook for event '%s' (uuid: %s) due to http problems: %+v", webhook.Event, webhook.Uuid, err)
-	} else {
-		debugWebhook.Debugf("fired webhook for event '%s' (uuid: %s)", webhook.Event, webhook.Uuid)
+		return attempt
 	}
+	defer res.Body.Close()
+
+	attempt.Status = res.StatusCode
+	b, _ := io.ReadAll(io.LimitReader(res.Body, webhookResponseSize))
+	attempt.Response = string(b)
+	return attempt
 }